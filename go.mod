@@ -3,8 +3,9 @@ module github.com/ionutinit/riddles-api
 go 1.20
 
 require (
-	github.com/lib/pq v1.10.9 // indirect
-	github.com/sashabaranov/go-openai v1.17.9 // indirect
-	github.com/sirupsen/logrus v1.9.3 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	github.com/lib/pq v1.10.9
+	github.com/sashabaranov/go-openai v1.17.9
+	github.com/sirupsen/logrus v1.9.3
 )
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
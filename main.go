@@ -6,91 +6,206 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
 	"github.com/ionutinit/riddles-api/pkg/config"
 	"github.com/ionutinit/riddles-api/pkg/db"
 	"github.com/ionutinit/riddles-api/pkg/handlers"
 	"github.com/ionutinit/riddles-api/pkg/logger"
 	"github.com/ionutinit/riddles-api/pkg/middleware"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/requestid"
+	"github.com/ionutinit/riddles-api/pkg/router"
 )
 
-func rootRouteHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
-		handlers.GetAllRiddlesHandler(w, r)
-	case "POST":
-		handlers.PostRiddleHandler(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// defaultCachePolicies is the Cache-Control of the public read routes, config can replace any of them
+var defaultCachePolicies = map[string]string{
+	"/api/riddles":                        "public, max-age=60",
+	"/api/riddles/random":                 "no-store",
+	"/api/riddles/search":                 "public, max-age=60",
+	"/api/riddles/daily":                  "public, max-age=3600",
+	"/api/riddles/{id:int}":               "public, max-age=300",
+	"/api/riddles/{id:int}/solution":      "public, max-age=300",
+	"/api/riddles/{id:int}/hints/{n:int}": "public, max-age=300",
+	"/api/tags":                           "public, max-age=300",
+	"/api/sessions/{id}/next":             "no-store",
+	"/api/openapi.json":                   "public, max-age=3600",
 }
 
-func singleRiddleHandler(allowedIPs []string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "GET":
-			handlers.GetRiddleByIdHandler(w, r)
-		case "DELETE":
-			middleware.IPWhitelistMiddleware(http.HandlerFunc(handlers.DeleteRiddleHandler), allowedIPs).ServeHTTP(w, r)
-		case "PATCH":
-			middleware.IPWhitelistMiddleware(http.HandlerFunc(handlers.PatchRiddleHandler), allowedIPs).ServeHTTP(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// cachePolicies keys the policies by the patterns registered under prefix, /api/v2/riddles shares the policy of /api/riddles
+func cachePolicies(prefix string) map[string]string {
+	policies := map[string]string{}
+	for _, source := range []map[string]string{defaultCachePolicies, config.AppConfig.Caching.Policies} {
+		for pattern, policy := range source {
+			policies[prefix+strings.TrimPrefix(pattern, "/api")] = policy
 		}
 	}
+	return policies
 }
 
-func generateImage(allowedIPS []string) http.HandlerFunc{
-	return func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "GET":
-			middleware.IPWhitelistMiddleware(http.HandlerFunc(handlers.GenerateImageHandler), allowedIPS).ServeHTTP(w, r)	
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// v2Released is when v1 was deprecated, unless config says otherwise
+var v2Released = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+func v1Lifecycle() apiversion.Lifecycle {
+	lifecycle := apiversion.Lifecycle{Deprecated: v2Released}
+
+	for _, date := range []struct {
+		value  string
+		target *time.Time
+	}{
+		{config.AppConfig.Versioning.V1Deprecated, &lifecycle.Deprecated},
+		{config.AppConfig.Versioning.V1Sunset, &lifecycle.Sunset},
+	} {
+		if date.value == "" {
+			continue
 		}
+		parsed, err := time.Parse("2006-01-02", date.value)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"date":  date.value,
+				"error": err,
+			}).Warn("Invalid versioning date in config, expected YYYY-MM-DD")
+			continue
+		}
+		*date.target = parsed
 	}
+	return lifecycle
 }
 
-func main() {
+// registerRoutes wires every endpoint. The API is served unversioned under /api, where Accept picks the version,
+// and pinned to a version under /api/v1 and /api/v2.
+func registerRoutes(rt *router.Router, allowedIPs []string) {
+	lifecycle := v1Lifecycle()
+	registerAPI(rt.Group("/api", apiversion.Negotiate(lifecycle)), "/api", allowedIPs)
+	registerAPI(rt.Group("/api/v1", apiversion.Pin(apiversion.V1, lifecycle)), "/api/v1", allowedIPs)
+	registerAPI(rt.Group("/api/v2", apiversion.Pin(apiversion.V2, lifecycle)), "/api/v2", allowedIPs)
 
-	config.LoadConfig("config.json")
+	fs := http.FileServer(http.Dir("templates"))
+	rt.Handle("GET", "/static/{path...}", http.StripPrefix("/static/", fs))
 
-	db.InitDB()
-	defer db.GetDB().Close()
+	// API page with the explorer, and the OpenAPI document it reads
+	policies := cachePolicies("/api")
+	rt.HandleFunc("GET", "/api", handlers.ApiPageHandler)
+	rt.HandleFunc("GET", "/api/openapi.json", handlers.OpenAPIHandler, func(next http.Handler) http.Handler {
+		return middleware.CacheControlMiddleware(next, policies)
+	})
+}
 
-	allowedIPs := config.AppConfig.AllowedIPs
+// registerAPI wires the endpoints of one version under prefix, restricted ones go through the IP whitelist
+// and the public read routes get their Cache-Control policy
+func registerAPI(api *router.Group, prefix string, allowedIPs []string) {
+	restricted := func(next http.Handler) http.Handler {
+		return middleware.IPWhitelistMiddleware(next, allowedIPs)
+	}
+	policies := cachePolicies(prefix)
+	cached := func(next http.Handler) http.Handler {
+		return middleware.CacheControlMiddleware(next, policies)
+	}
 
 	// GET all and POST
-	http.HandleFunc("/api/riddles", rootRouteHandler)
+	api.HandleFunc("GET", "/riddles", handlers.GetAllRiddlesHandler, cached)
+	api.HandleFunc("POST", "/riddles", handlers.PostRiddleHandler)
+
+	// POST bulk import from JSON, NDJSON or CSV, IP-protected
+	api.HandleFunc("POST", "/riddles/import", handlers.ImportRiddlesHandler, restricted)
+
+	// GET streaming export as JSON, NDJSON or CSV, unpublished riddles and metadata only for allowed IPs
+	api.HandleFunc("GET", "/riddles/export", handlers.ExportRiddlesHandler)
 
 	// GET random riddle
-	http.HandleFunc("/api/riddles/random", handlers.RandomRiddleHandler)
+	api.HandleFunc("GET", "/riddles/random", handlers.RandomRiddleHandler, cached)
+
+	// GET full-text search
+	api.HandleFunc("GET", "/riddles/search", handlers.SearchRiddlesHandler, cached)
+
+	// GET riddle of the day, overriding it for a date is IP-protected
+	api.HandleFunc("GET", "/riddles/daily", handlers.GetDailyRiddleHandler, cached)
+	api.HandleFunc("PUT", "/riddles/daily/{date}", handlers.ScheduleDailyRiddleHandler, restricted)
+	api.HandleFunc("DELETE", "/riddles/daily/{date}", handlers.UnscheduleDailyRiddleHandler, restricted)
+
+	// GET, PUT, DELETE, PATCH single riddle by id
+	// PUT, DELETE and PATCH methods are IP-protected
+	api.HandleFunc("GET", "/riddles/{id:int}", handlers.GetRiddleByIdHandler, cached)
+	api.HandleFunc("PUT", "/riddles/{id:int}", handlers.PutRiddleHandler, restricted)
+	api.HandleFunc("DELETE", "/riddles/{id:int}", handlers.DeleteRiddleHandler, restricted)
+	api.HandleFunc("PATCH", "/riddles/{id:int}", handlers.PatchRiddleHandler, restricted)
+
+	// checking an answer does not reveal the solution, revealing it is explicit
+	api.HandleFunc("POST", "/riddles/{id:int}/guess", handlers.GuessRiddleHandler)
+	api.HandleFunc("GET", "/riddles/{id:int}/solution", handlers.RevealSolutionHandler, cached)
+
+	// hints are revealed one at a time, managing them is IP-protected
+	api.HandleFunc("GET", "/riddles/{id:int}/hints/{n:int}", handlers.GetHintHandler, cached)
+	api.HandleFunc("POST", "/riddles/{id:int}/hints", handlers.PostHintHandler, restricted)
+	api.HandleFunc("PATCH", "/riddles/{id:int}/hints", handlers.ReorderHintsHandler, restricted)
+	api.HandleFunc("DELETE", "/riddles/{id:int}/hints/{n:int}", handlers.DeleteHintHandler, restricted)
+
+	// adding and removing synonyms is IP-protected like PATCH
+	api.HandleFunc("POST", "/riddles/{id:int}/synonyms", handlers.PostSynonymHandler, restricted)
+	api.HandleFunc("DELETE", "/riddles/{id:int}/synonyms/{synonym}", handlers.DeleteSynonymHandler, restricted)
+
+	// revisions expose solutions and submitter emails, so they are IP-protected as a whole
+	api.HandleFunc("GET", "/riddles/{id:int}/revisions", handlers.GetRevisionsHandler, restricted)
+	api.HandleFunc("GET", "/riddles/{id:int}/revisions/diff", handlers.DiffRevisionsHandler, restricted)
+	api.HandleFunc("POST", "/riddles/{id:int}/revisions/{rev:int}/revert", handlers.RevertRevisionHandler, restricted)
+
+	// GET tags with the number of riddles using them
+	api.HandleFunc("GET", "/tags", handlers.GetTagsHandler, cached)
+
+	// POST creates a play session, GET next serves its riddles without repeats
+	api.HandleFunc("POST", "/sessions", handlers.PostSessionHandler)
+	// every request moves the session on, HEAD would skip a riddle unseen
+	api.HandleFunc("GET", "/sessions/{id}/next", handlers.NextSessionRiddleHandler, cached).WithoutHead()
 
-	// GET, DELETE, PATCH single riddle by id
-	// DELETE and PATCH methods are IP-protected
-	http.HandleFunc("/api/riddles/", singleRiddleHandler(allowedIPs))
+	// review queue, approve and reject, trash and restore, duplicate report, all IP-protected
+	api.HandleFunc("GET", "/admin/riddles/pending", handlers.GetPendingRiddlesHandler, restricted)
+	api.HandleFunc("POST", "/admin/riddles/{id:int}/approve", handlers.ApproveRiddleHandler, restricted)
+	api.HandleFunc("POST", "/admin/riddles/{id:int}/reject", handlers.RejectRiddleHandler, restricted)
+	api.HandleFunc("GET", "/admin/riddles/trash", handlers.GetTrashHandler, restricted)
+	api.HandleFunc("POST", "/admin/riddles/{id:int}/restore", handlers.RestoreRiddleHandler, restricted)
+	api.HandleFunc("GET", "/admin/riddles/duplicates", handlers.GetDuplicatesHandler, restricted)
 
 	// DALLE
-	// http.HandleFunc("/api/riddles/image/", handlers.GenerateImageHandler)
-	http.HandleFunc("/api/riddles/image/", generateImage(allowedIPs))
+	api.HandleFunc("GET", "/riddles/image/{id:int}", handlers.GenerateImageHandler, restricted)
 
-	fs := http.FileServer(http.Dir("templates"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+}
+
+func main() {
+
+	config.LoadConfig("config.json")
 
-	http.HandleFunc("/api", handlers.ApiPageHandler)
+	db.InitDB()
+	defer db.GetDB().Close()
+
+	allowedIPs := config.AppConfig.AllowedIPs
+
+	rt := router.New()
+	rt.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "No route matches the request")
+	})
+	rt.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "Method not allowed")
+	})
+	registerRoutes(rt, allowedIPs)
 
 	server := &http.Server{
 		Addr:    ":" + config.AppConfig.ServerPort,
-		Handler: nil, //a nil handler defaults to http.DefaultServeMux
+		Handler: requestid.Middleware(rt),
 	}
 
 	// starting the server in a go routine
 	go startServer(server)
 
+	// purging the trash in the background until shutdown
+	stopPurge := make(chan struct{})
+	go purgeTrash(stopPurge)
+
 	// channel listening for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -98,6 +213,7 @@ func main() {
 	// blocks until a signal is received
 	<-quit
 	logger.Log.Info("Shutting down server...")
+	close(stopPurge)
 
 	// creates a deadline
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -125,3 +241,38 @@ func startServer(server *http.Server) {
 		}).Fatal("Server start failed")
 	}
 }
+
+func purgeTrash(stop <-chan struct{}) {
+	retentionDays := config.AppConfig.Trash.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = 30
+	}
+
+	interval := time.Duration(config.AppConfig.Trash.PurgeIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := db.PurgeDeletedRiddles(retentionDays)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Error purging deleted riddles")
+		} else if purged > 0 {
+			logger.Log.WithFields(logrus.Fields{
+				"purged":        purged,
+				"retentionDays": retentionDays,
+			}).Info("Purged deleted riddles")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
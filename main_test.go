@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ionutinit/riddles-api/pkg/openapi"
+	"github.com/ionutinit/riddles-api/pkg/router"
+)
+
+// routeParam matches the router's typed parameters, {id:int} and {path...} are plain {id} and {path} in OpenAPI
+var routeParam = regexp.MustCompile(`\{(\w+)(?::int|\.\.\.)?\}`)
+
+func specOperations(t *testing.T) map[string]map[string]interface{} {
+	t.Helper()
+
+	var spec struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+	return spec.Paths
+}
+
+// versionPrefix matches the versioned groups, they serve the same paths as /api so the document describes them once
+var versionPrefix = regexp.MustCompile(`^/api/v\d+/`)
+
+func specPath(pattern string) string {
+	return routeParam.ReplaceAllString(versionPrefix.ReplaceAllString(pattern, "/api/"), "{$1}")
+}
+
+// static files are not part of the API
+func documented(route router.Route) bool {
+	return !strings.HasPrefix(route.Pattern, "/static/")
+}
+
+func TestEveryRouteIsInTheSpec(t *testing.T) {
+	paths := specOperations(t)
+
+	rt := router.New()
+	registerRoutes(rt, nil)
+
+	for _, route := range rt.Routes() {
+		if !documented(route) {
+			continue
+		}
+		path := specPath(route.Pattern)
+		if _, ok := paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is registered but missing from the OpenAPI document as %s", route.Method, route.Pattern, path)
+		}
+	}
+}
+
+func TestEverySpecOperationIsRegistered(t *testing.T) {
+	paths := specOperations(t)
+
+	rt := router.New()
+	registerRoutes(rt, nil)
+
+	registered := map[string]bool{}
+	for _, route := range rt.Routes() {
+		registered[route.Method+" "+specPath(route.Pattern)] = true
+	}
+
+	for path, operations := range paths {
+		for method := range operations {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is in the OpenAPI document but no route is registered for it", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
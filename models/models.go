@@ -1,12 +1,19 @@
 package models
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
 type RiddleBase struct {
-	ID       int     `json:"id"`
-	Riddle   string  `json:"riddle"`
-	Solution string  `json:"solution"`
-	Synonyms *string `json:"synonyms,omitempty"`
+	ID       int      `json:"id"`
+	Riddle   string   `json:"riddle"`
+	Solution string   `json:"solution,omitempty"`
+	Synonyms []string `json:"synonyms,omitempty"`
+	// Version grows with every change to the riddle, it backs the ETag of the riddle
+	Version int `json:"-"`
+	// LastModified backs the Last-Modified header of the riddle
+	LastModified time.Time `json:"-"`
 }
 
 type Link struct {
@@ -18,9 +25,181 @@ type Riddle struct {
 	RiddleBase
 	Username  sql.NullString `json:"username,omitempty"`
 	UserEmail sql.NullString `json:"user_email,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
 }
 
 type RiddleResponse struct {
 	RiddleBase
+	Tags []string `json:"tags,omitempty"`
+	// only set for riddles that are not public yet
+	Status string `json:"status,omitempty"`
+	// near duplicates found when the riddle was submitted
+	Warnings []DuplicateWarning `json:"warnings,omitempty"`
+	Links    []Link             `json:"links,omitempty"`
+}
+
+// AdminRiddle is the full view of a riddle used by the admin endpoints
+type AdminRiddle struct {
+	RiddleBase
+	Username        *string    `json:"username,omitempty"`
+	UserEmail       *string    `json:"user_email,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	Published       bool       `json:"published"`
+	DateCreated     time.Time  `json:"date_created"`
+	ReviewedBy      *string    `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason *string    `json:"rejection_reason,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	Links           []Link     `json:"links,omitempty"`
+}
+
+type Hint struct {
+	ID       int    `json:"id"`
+	RiddleID int    `json:"riddle_id"`
+	Position int    `json:"position"`
+	Hint     string `json:"hint"`
+}
+
+type HintResponse struct {
+	RiddleID int    `json:"riddle_id"`
+	Number   int    `json:"number"`
+	Total    int    `json:"total"`
+	Hint     string `json:"hint"`
+	// derived hints are generated from the solution when no curated hints exist
+	Derived bool   `json:"derived"`
+	Links   []Link `json:"links,omitempty"`
+}
+
+type PlaySession struct {
+	ID        string    `json:"id"`
+	Position  int       `json:"position"`
+	Total     int       `json:"total"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RiddleList struct {
+	Items []RiddleResponse `json:"items"`
+	Total int              `json:"total"`
+	Links []Link           `json:"links,omitempty"`
+}
+
+type SearchResult struct {
+	RiddleResponse
+	Rank float64 `json:"rank"`
+	// the riddle text escaped as HTML, with the matches wrapped in <mark></mark>
+	Snippet string `json:"snippet"`
+}
+
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
 	Links []Link `json:"links,omitempty"`
 }
+
+type Revision struct {
+	Revision    int       `json:"revision"`
+	Riddle      string    `json:"riddle"`
+	Solution    string    `json:"solution"`
+	Synonyms    []string  `json:"synonyms,omitempty"`
+	Username    *string   `json:"username,omitempty"`
+	UserEmail   *string   `json:"user_email,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	ChangeType  string    `json:"change_type"`
+	ChangedBy   *string   `json:"changed_by,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+	Links       []Link    `json:"links,omitempty"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// ImportRow is the outcome of one imported riddle, rows are numbered from 1 in the order they were sent
+type ImportRow struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	ID     *int     `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+	Links  []Link   `json:"links,omitempty"`
+}
+
+type ImportReport struct {
+	Mode    string      `json:"mode"`
+	DryRun  bool        `json:"dry_run"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// DuplicateWarning points to an existing riddle with similar text and the same solution
+type DuplicateWarning struct {
+	Message    string  `json:"message"`
+	ID         int     `json:"id"`
+	Riddle     string  `json:"riddle"`
+	Similarity float64 `json:"similarity"`
+	// only published riddles get a view link
+	Published bool   `json:"-"`
+	Links     []Link `json:"links,omitempty"`
+}
+
+type DuplicateCluster struct {
+	// exact clusters share the same normalized riddle text
+	Exact   bool          `json:"exact"`
+	Riddles []AdminRiddle `json:"riddles"`
+}
+
+// RiddleV2 is a riddle as served by v2 of the API, the same riddle as RiddleResponse in a stricter shape:
+// every field is always present, a hidden solution is null and synonyms and tags are arrays, possibly empty
+type RiddleV2 struct {
+	ID       int      `json:"id"`
+	Riddle   string   `json:"riddle"`
+	Solution *string  `json:"solution"`
+	Synonyms []string `json:"synonyms"`
+	Tags     []string `json:"tags"`
+	// only set for riddles that are not public yet
+	Status   string             `json:"status,omitempty"`
+	Warnings []DuplicateWarning `json:"warnings,omitempty"`
+	Links    []Link             `json:"links"`
+}
+
+// AdminRiddleV2 is the full view of a riddle served to admins by v2 of the API, the fields without a value are null
+type AdminRiddleV2 struct {
+	RiddleV2
+	Username        *string    `json:"username"`
+	UserEmail       *string    `json:"user_email"`
+	Published       bool       `json:"published"`
+	DateCreated     time.Time  `json:"date_created"`
+	ReviewedBy      *string    `json:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	RejectionReason *string    `json:"rejection_reason"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+}
+
+// DuplicateClusterV2 is a DuplicateCluster as served by v2 of the API
+type DuplicateClusterV2 struct {
+	Exact   bool            `json:"exact"`
+	Riddles []AdminRiddleV2 `json:"riddles"`
+}
+
+type SearchResultV2 struct {
+	RiddleV2
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// ListMeta describes a v2 collection apart from its items
+type ListMeta struct {
+	Total int `json:"total"`
+	// the search terms, for search results only
+	Query string `json:"query,omitempty"`
+}
+
+// ListV2 is the envelope of every v2 collection, the items in data and the pagination links next to them
+type ListV2 struct {
+	Data  interface{} `json:"data"`
+	Meta  ListMeta    `json:"meta"`
+	Links []Link      `json:"links"`
+}
@@ -0,0 +1,227 @@
+package answers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// possible outcomes of checking an answer
+const (
+	Correct = "correct"
+	Close   = "close"
+	Wrong   = "wrong"
+)
+
+// Tolerance controls how many typos are forgiven, as a share of the expected answer's length
+type Tolerance struct {
+	// answers within CorrectRatio edits per character still count as correct
+	CorrectRatio float64
+	// answers within CloseRatio edits per character are reported as close
+	CloseRatio float64
+	// answers shorter than MinLength characters must match exactly
+	MinLength int
+}
+
+// DefaultTolerance forgives about one typo in seven characters, a zero Tolerance only accepts exact matches
+var DefaultTolerance = Tolerance{
+	CorrectRatio: 0.15,
+	CloseRatio:   0.35,
+	MinLength:    4,
+}
+
+// MaxAnswerLength bounds the answers worth comparing, solutions are never longer
+const MaxAnswerLength = 255
+
+// articles are dropped during normalization so that "a candle" matches "candle"
+var articles = map[string]bool{
+	"a":   true,
+	"an":  true,
+	"the": true,
+}
+
+// Normalize lowercases the input, folds diacritics, strips punctuation, drops articles and collapses whitespace
+func Normalize(s string) string {
+	s = foldDiacritics(strings.ToLower(s))
+
+	runes := []rune(s)
+	cleaned := make([]rune, 0, len(runes))
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cleaned = append(cleaned, r)
+		// apostrophes are removed rather than turned into spaces, so "man's" stays one word
+		case r == '\'' || r == '’':
+		// thousands separators are removed, so "1,000" stays one number
+		case r == ',' && i > 0 && i < len(runes)-1 && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1]):
+		default:
+			cleaned = append(cleaned, ' ')
+		}
+	}
+
+	var words []string
+	for _, word := range strings.Fields(string(cleaned)) {
+		if articles[word] {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Canonical normalizes the input, spells numbers as digits and folds plurals
+func Canonical(s string) string {
+	return stemWords(numberWordsToDigits(strings.Fields(Normalize(s))))
+}
+
+// spelled keeps number words as written, so typos inside them can still be measured
+func spelled(s string) string {
+	return stemWords(strings.Fields(Normalize(s)))
+}
+
+func stemWords(words []string) string {
+	for i, word := range words {
+		words[i] = stem(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// Candidates returns the solution followed by every synonym
+func Candidates(solution string, synonyms []string) []string {
+	candidates := []string{solution}
+	for _, synonym := range synonyms {
+		if strings.TrimSpace(synonym) != "" {
+			candidates = append(candidates, synonym)
+		}
+	}
+	return candidates
+}
+
+// Check compares the answer with the solution and every synonym, returning Correct, Close or Wrong.
+// Answers longer than MaxAnswerLength are Wrong without being measured.
+func Check(answer string, solution string, synonyms []string, tolerance Tolerance) string {
+	// a close answer is never more forgiving than a correct one
+	if tolerance.CloseRatio < tolerance.CorrectRatio {
+		tolerance.CloseRatio = tolerance.CorrectRatio
+	}
+
+	if len([]rune(answer)) > MaxAnswerLength {
+		return Wrong
+	}
+	canonicalAnswer := Canonical(answer)
+	if canonicalAnswer == "" {
+		return Wrong
+	}
+
+	result := Wrong
+	for _, candidate := range Candidates(solution, synonyms) {
+		canonicalCandidate := Canonical(candidate)
+		if canonicalCandidate == canonicalAnswer {
+			return Correct
+		}
+
+		length := len([]rune(spelled(candidate)))
+		if length < tolerance.MinLength {
+			continue
+		}
+
+		distance := minInt(
+			levenshtein(canonicalAnswer, canonicalCandidate),
+			levenshtein(spelled(answer), spelled(candidate)),
+		)
+		if distance <= int(float64(length)*tolerance.CorrectRatio) {
+			return Correct
+		}
+		if distance <= int(float64(length)*tolerance.CloseRatio) {
+			result = Close
+		}
+	}
+	return result
+}
+
+// levenshtein counts the single character insertions, deletions and substitutions between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+// stem is a light plural folding, applied to both sides so only consistency matters
+func stem(word string) string {
+	if len(word) <= 3 || !strings.HasSuffix(word, "s") || isDigits(word) {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	default:
+		return strings.TrimSuffix(word, "s")
+	}
+}
+
+func isDigits(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'ş': "s", 'ș': "s", 'š': "s", 'ß': "ss",
+	'ţ': "t", 'ț': "t", 'ť': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// foldDiacritics maps accented latin letters to their plain equivalents, the input must already be lowercase
+func foldDiacritics(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if folded, ok := diacritics[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
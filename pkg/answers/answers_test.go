@@ -0,0 +1,95 @@
+package answers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	exact := Tolerance{}
+
+	tests := []struct {
+		name      string
+		answer    string
+		solution  string
+		synonyms  []string
+		tolerance Tolerance
+		want      string
+	}{
+		{"exact", "candle", "candle", nil, DefaultTolerance, Correct},
+		{"case and article", "A Candle", "candle", nil, DefaultTolerance, Correct},
+		{"punctuation and diacritics", "  échö! ", "echo", nil, DefaultTolerance, Correct},
+		{"plural", "candles", "candle", nil, DefaultTolerance, Correct},
+		{"number words", "twenty one", "21", nil, DefaultTolerance, Correct},
+		{"number word sequences are not summed", "one two", "3", nil, DefaultTolerance, Wrong},
+		{"repeated multipliers are not numbers", "hundred hundred", "10000", nil, DefaultTolerance, Wrong},
+		{"unparsed number words compare as written", "one two", "one two", nil, DefaultTolerance, Correct},
+		{"synonym", "enigma", "riddle", []string{"puzzle", "enigma"}, DefaultTolerance, Correct},
+		{"one typo", "umbrela", "umbrella", nil, DefaultTolerance, Correct},
+		{"two typos is close", "umbrlaa", "umbrella", nil, DefaultTolerance, Close},
+		{"unrelated", "tomorrow", "umbrella", nil, DefaultTolerance, Wrong},
+		{"empty answer", " the ", "candle", nil, DefaultTolerance, Wrong},
+		{"short solutions match exactly", "ice", "icy", nil, DefaultTolerance, Wrong},
+		{"zero tolerance refuses typos", "umbrela", "umbrella", nil, exact, Wrong},
+		{"zero tolerance still normalizes", "The Umbrellas", "umbrella", nil, exact, Correct},
+		{"close never stricter than correct", "umbrela", "umbrella", nil, Tolerance{CorrectRatio: 0.15, MinLength: 4}, Correct},
+		{"too long", strings.Repeat("a", MaxAnswerLength+1), strings.Repeat("a", 10), nil, DefaultTolerance, Wrong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(tt.answer, tt.solution, tt.synonyms, tt.tolerance); got != tt.want {
+				t.Errorf("Check(%q, %q) = %s, want %s", tt.answer, tt.solution, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"The Man's Shadow!": "mans shadow",
+		"1,000 years":       "1000 years",
+		"Crème  brûlée":     "creme brulee",
+		"an egg":            "egg",
+	}
+
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestNumberWordsToDigits(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"twenty one", "21"},
+		{"zero", "0"},
+		{"hundred", "100"},
+		{"one hundred and five", "105"},
+		{"two thousand and twenty four", "2024"},
+		{"thousand years", "1000 years"},
+		{"three million two hundred and five thousand", "3205000"},
+		{"nine hundred ninety nine billion nine hundred ninety nine million nine hundred ninety nine thousand nine hundred ninety nine", "999999999999"},
+		{"bread and one", "bread and 1"},
+		{"seven hundred and cats", "700 and cats"},
+		{"one two", "one two"},
+		{"twenty ten", "twenty ten"},
+		{"zero zero", "zero zero"},
+		{"hundred hundred", "hundred hundred"},
+		{"one and two", "one and two"},
+		{"one thousand million", "one thousand million"},
+		{"thousand thousand", "thousand thousand"},
+		{strings.Repeat("billion ", 40), strings.TrimSpace(strings.Repeat("billion ", 40))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := strings.Join(numberWordsToDigits(strings.Fields(tt.input)), " "); got != tt.want {
+				t.Errorf("numberWordsToDigits(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
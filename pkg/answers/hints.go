@@ -0,0 +1,44 @@
+package answers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DerivedHints builds progressively revealing hints from the solution itself,
+// used when a riddle has no curated hints
+func DerivedHints(solution string) []string {
+	words := strings.Fields(solution)
+	if len(words) == 0 {
+		return nil
+	}
+
+	letters := 0
+	var first rune
+	for _, r := range solution {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if letters == 0 {
+			first = unicode.ToUpper(r)
+		}
+		letters++
+	}
+
+	hints := []string{pluralize(len(words), "The answer has %d word", "The answer has %d words")}
+	if letters > 0 {
+		hints = append(hints,
+			pluralize(letters, "The answer has %d letter", "The answer has %d letters"),
+			fmt.Sprintf("The answer starts with \"%c\"", first),
+		)
+	}
+	return hints
+}
+
+func pluralize(count int, singular string, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf(singular, count)
+	}
+	return fmt.Sprintf(plural, count)
+}
@@ -0,0 +1,142 @@
+package answers
+
+import "strconv"
+
+var smallNumbers = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19,
+	"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+var scaleNumbers = map[string]int{
+	"thousand": 1000,
+	"million":  1000000,
+	"billion":  1000000000,
+}
+
+func isNumberWord(word string) bool {
+	_, small := smallNumbers[word]
+	_, scale := scaleNumbers[word]
+	return small || scale || word == "hundred"
+}
+
+// numberWordsToDigits replaces runs of spelled out numbers with digits, so "twenty one" becomes "21".
+// Runs that are not a well-formed number, such as "one two", are kept as written.
+func numberWordsToDigits(words []string) []string {
+	var result []string
+	var run []string
+
+	flush := func() {
+		if value, ok := parseNumber(run); ok {
+			result = append(result, strconv.FormatInt(value, 10))
+		} else {
+			result = append(result, run...)
+		}
+		run = nil
+	}
+
+	for i, word := range words {
+		// "and" only belongs to the number when it joins two number words, e.g. "one hundred and one"
+		if word == "and" && len(run) > 0 && i+1 < len(words) && isNumberWord(words[i+1]) {
+			run = append(run, word)
+			continue
+		}
+		if isNumberWord(word) {
+			run = append(run, word)
+			continue
+		}
+
+		flush()
+		result = append(result, word)
+	}
+	flush()
+
+	return result
+}
+
+// parseNumber reads a spelled out number made of groups below a thousand, each followed by a smaller scale than the one before,
+// e.g. "three million two hundred and five thousand". Anything else, such as "hundred hundred", is not a number.
+// Groups and scales are bounded, so the value always fits.
+func parseNumber(words []string) (int64, bool) {
+	if len(words) == 0 {
+		return 0, false
+	}
+	if len(words) == 1 && words[0] == "zero" {
+		return 0, true
+	}
+
+	var total, lastScale int64
+	i := 0
+	for {
+		group, next := parseGroup(words, i, i == 0)
+		i = next
+		if i == len(words) {
+			return total + group, true
+		}
+
+		scale, ok := scaleNumbers[words[i]]
+		if !ok || (lastScale != 0 && int64(scale) >= lastScale) {
+			return 0, false
+		}
+		// a bare scale only opens a number, "a thousand" is 1000
+		if group == 0 {
+			if i != 0 {
+				return 0, false
+			}
+			group = 1
+		}
+		total += group * int64(scale)
+		lastScale = int64(scale)
+		i++
+
+		if i < len(words) && words[i] == "and" {
+			i++
+		}
+		if i == len(words) {
+			return total, words[i-1] != "and"
+		}
+	}
+}
+
+// parseGroup reads the number below a thousand starting at words[i], e.g. "two hundred and forty one", and returns the index after it.
+// A bare "hundred" is only read at the start of a number, as in "a hundred".
+func parseGroup(words []string, i int, first bool) (int64, int) {
+	var value int64
+
+	hundreds := false
+	if i+1 < len(words) && words[i+1] == "hundred" {
+		if units, ok := smallNumbers[words[i]]; ok && units >= 1 && units <= 9 {
+			value = int64(units) * 100
+			i += 2
+			hundreds = true
+		}
+	} else if first && i < len(words) && words[i] == "hundred" {
+		value = 100
+		i++
+		hundreds = true
+	}
+	if hundreds && i+1 < len(words) && words[i] == "and" {
+		if rest, ok := smallNumbers[words[i+1]]; ok && rest != 0 {
+			i++
+		}
+	}
+
+	if i < len(words) {
+		if rest, ok := smallNumbers[words[i]]; ok && rest != 0 {
+			value += int64(rest)
+			i++
+			// tens take a single unit, "twenty one" but not "twenty ten"
+			if rest >= 20 && i < len(words) {
+				if units, ok := smallNumbers[words[i]]; ok && units >= 1 && units <= 9 {
+					value += int64(units)
+					i++
+				}
+			}
+		}
+	}
+
+	return value, i
+}
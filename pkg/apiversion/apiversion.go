@@ -0,0 +1,147 @@
+// Package apiversion decides which version of the API answers a request.
+//
+// Routes under /api/v1 and /api/v2 are pinned to their version. The unversioned /api routes are v1,
+// unless the client asks for v2 with Accept: application/vnd.riddles.v2+json.
+// v1 responses announce the deprecation of v1 with the Deprecation, Sunset and Link headers.
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	V1 = 1
+	V2 = 2
+)
+
+// V2MediaType is the media type clients ask for in Accept, and the one v2 JSON responses are sent as
+const V2MediaType = "application/vnd.riddles.v2+json"
+
+// Lifecycle tells when v1 was deprecated and when it is going away, a zero Sunset leaves the header out
+type Lifecycle struct {
+	Deprecated time.Time
+	Sunset     time.Time
+}
+
+type versionKey struct{}
+
+type prefixKey struct{}
+
+type writerKey struct{}
+
+// FromRequest returns the version answering the request, v1 when none was picked
+func FromRequest(r *http.Request) int {
+	if version, ok := r.Context().Value(versionKey{}).(int); ok {
+		return version
+	}
+	return V1
+}
+
+// PathPrefix returns /api/v1 or /api/v2 when the version was picked by the path, so links can stay under it
+func PathPrefix(r *http.Request) string {
+	prefix, _ := r.Context().Value(prefixKey{}).(string)
+	return prefix
+}
+
+// Pin answers the routes under /api/v<version> with that version, whatever the Accept header says
+func Pin(version int, lifecycle Lifecycle) func(http.Handler) http.Handler {
+	prefix := fmt.Sprintf("/api/v%d", version)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), versionKey{}, version)
+			r = r.WithContext(context.WithValue(ctx, prefixKey{}, prefix))
+			serve(next, w, r, version, lifecycle)
+		})
+	}
+}
+
+// Negotiate picks the version of the unversioned routes from the Accept header, v1 unless v2 is asked for
+func Negotiate(lifecycle Lifecycle) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the same URL answers differently depending on Accept, caches must keep the versions apart
+			w.Header().Add("Vary", "Accept")
+
+			version := V1
+			for _, accepted := range strings.Split(strings.Join(r.Header.Values("Accept"), ","), ",") {
+				mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+				if err == nil && mediaType == V2MediaType {
+					version = V2
+					break
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), versionKey{}, version))
+			serve(next, w, r, version, lifecycle)
+		})
+	}
+}
+
+func serve(next http.Handler, w http.ResponseWriter, r *http.Request, version int, lifecycle Lifecycle) {
+	if version == V1 {
+		deprecate(w, r, lifecycle)
+		next.ServeHTTP(w, r)
+		return
+	}
+	writer := &mediaTypeWriter{ResponseWriter: w}
+	next.ServeHTTP(writer, r.WithContext(context.WithValue(r.Context(), writerKey{}, writer)))
+}
+
+// Unversioned marks the response to the request as having the same shape in every version, under v2 it keeps
+// application/json instead of being sent as V2MediaType. Handlers call it before writing the response.
+func Unversioned(r *http.Request) {
+	if writer, ok := r.Context().Value(writerKey{}).(*mediaTypeWriter); ok {
+		writer.unversioned = true
+	}
+}
+
+// deprecate sets the RFC 9745 Deprecation and RFC 8594 Sunset headers, linking to the v2 counterpart of the resource
+func deprecate(w http.ResponseWriter, r *http.Request, lifecycle Lifecycle) {
+	w.Header().Set("Deprecation", fmt.Sprintf("@%d", lifecycle.Deprecated.Unix()))
+	if !lifecycle.Sunset.IsZero() {
+		w.Header().Set("Sunset", lifecycle.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	if PathPrefix(r) != "" {
+		successor := "/api/v2" + strings.TrimPrefix(r.URL.EscapedPath(), PathPrefix(r))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		return
+	}
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"; type="%s"`, r.URL.EscapedPath(), V2MediaType))
+}
+
+// mediaTypeWriter sends the JSON of v2 responses as V2MediaType, problems keep application/problem+json
+// and unversioned responses application/json
+type mediaTypeWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	unversioned bool
+}
+
+func (w *mediaTypeWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "application/json" && !w.unversioned {
+			w.Header().Set("Content-Type", V2MediaType)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *mediaTypeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *mediaTypeWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,197 @@
+package apiversion
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var lifecycle = Lifecycle{
+	Deprecated: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+	Sunset:     time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+}
+
+// recorded is what the handler behind the middleware saw of the request
+type recorded struct {
+	version int
+	prefix  string
+}
+
+// handler answers with the given content type and records the version and prefix picked for the request
+func handler(contentType string, got *recorded) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = recorded{version: FromRequest(r), prefix: PathPrefix(r)}
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, "{}")
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      []string
+		lifecycle   Lifecycle
+		wantVersion int
+		wantType    string
+		wantSunset  string
+	}{
+		{"no accept", nil, lifecycle, V1, "application/json", "Fri, 30 Apr 2027 00:00:00 GMT"},
+		{"json", []string{"application/json"}, lifecycle, V1, "application/json", "Fri, 30 Apr 2027 00:00:00 GMT"},
+		{"no sunset", []string{"application/json"}, Lifecycle{Deprecated: lifecycle.Deprecated}, V1, "application/json", ""},
+		{"v2", []string{V2MediaType}, lifecycle, V2, V2MediaType, ""},
+		{"v2 with parameters", []string{"application/json, application/vnd.riddles.v2+json; q=0.9"}, lifecycle, V2, V2MediaType, ""},
+		{"v2 in a second header", []string{"text/html", V2MediaType}, lifecycle, V2, V2MediaType, ""},
+		{"malformed", []string{"application/vnd.riddles.v2+json;;"}, lifecycle, V1, "application/json", "Fri, 30 Apr 2027 00:00:00 GMT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got recorded
+			req := httptest.NewRequest("GET", "/api/riddles/42", nil)
+			for _, accept := range tt.accept {
+				req.Header.Add("Accept", accept)
+			}
+			rec := httptest.NewRecorder()
+			Negotiate(tt.lifecycle)(handler("application/json", &got)).ServeHTTP(rec, req)
+
+			if got.version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", got.version, tt.wantVersion)
+			}
+			if got.prefix != "" {
+				t.Errorf("prefix = %q, want none", got.prefix)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("Vary = %q, want Accept", vary)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.wantType)
+			}
+			if sunset := rec.Header().Get("Sunset"); sunset != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", sunset, tt.wantSunset)
+			}
+
+			deprecation, link := rec.Header().Get("Deprecation"), rec.Header().Get("Link")
+			if tt.wantVersion == V1 {
+				if deprecation != "@1792281600" {
+					t.Errorf("Deprecation = %q, want @1792281600", deprecation)
+				}
+				if want := `</api/riddles/42>; rel="successor-version"; type="` + V2MediaType + `"`; link != want {
+					t.Errorf("Link = %q, want %q", link, want)
+				}
+			} else if deprecation != "" || link != "" {
+				t.Errorf("v2 response deprecated: Deprecation = %q, Link = %q", deprecation, link)
+			}
+		})
+	}
+}
+
+func TestPin(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		path       string
+		accept     string
+		wantPrefix string
+		wantLink   string
+	}{
+		{"v1", V1, "/api/v1/riddles/42", "", "/api/v1", `</api/v2/riddles/42>; rel="successor-version"`},
+		{"v1 asked for v2", V1, "/api/v1/riddles/42", V2MediaType, "/api/v1", `</api/v2/riddles/42>; rel="successor-version"`},
+		{"v1 escaped path", V1, "/api/v1/riddles/big%20cat", "", "/api/v1", `</api/v2/riddles/big%20cat>; rel="successor-version"`},
+		{"v2", V2, "/api/v2/riddles/42", "application/json", "/api/v2", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got recorded
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			Pin(tt.version, lifecycle)(handler("application/json", &got)).ServeHTTP(rec, req)
+
+			if got.version != tt.version || got.prefix != tt.wantPrefix {
+				t.Errorf("version, prefix = %d, %q, want %d, %q", got.version, got.prefix, tt.version, tt.wantPrefix)
+			}
+			if link := rec.Header().Get("Link"); link != tt.wantLink {
+				t.Errorf("Link = %q, want %q", link, tt.wantLink)
+			}
+			// the path already names the version, the response does not depend on Accept
+			if vary := rec.Header().Get("Vary"); vary != "" {
+				t.Errorf("Vary = %q, want none", vary)
+			}
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"application/json", V2MediaType},
+		{"application/json; charset=utf-8", V2MediaType},
+		{"application/problem+json", "application/problem+json"},
+		{"text/csv", "text/csv"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			var got recorded
+			rec := httptest.NewRecorder()
+			Pin(V2, lifecycle)(handler(tt.contentType, &got)).ServeHTTP(rec, httptest.NewRequest("GET", "/api/v2/riddles", nil))
+
+			if contentType := rec.Header().Get("Content-Type"); contentType != tt.want {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.want)
+			}
+		})
+	}
+
+	t.Run("explicit status", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Pin(V2, lifecycle)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, "{}")
+		})).ServeHTTP(rec, httptest.NewRequest("POST", "/api/v2/riddles", nil))
+
+		if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != V2MediaType {
+			t.Errorf("POST = %d %q, want 201 %q", rec.Code, rec.Header().Get("Content-Type"), V2MediaType)
+		}
+	})
+}
+
+func TestUnversioned(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		want       string
+	}{
+		{"v2", Pin(V2, lifecycle), "application/json"},
+		{"negotiated v2", Negotiate(lifecycle), "application/json"},
+		{"v1", Pin(V1, lifecycle), "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/riddles/42/hints/1", nil)
+			req.Header.Set("Accept", V2MediaType)
+			rec := httptest.NewRecorder()
+			tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Unversioned(r)
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, "{}")
+			})).ServeHTTP(rec, req)
+
+			if contentType := rec.Header().Get("Content-Type"); contentType != tt.want {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.want)
+			}
+		})
+	}
+
+	// outside of the version middleware there is nothing to mark
+	Unversioned(httptest.NewRequest("GET", "/api/riddles/42/hints/1", nil))
+}
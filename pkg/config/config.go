@@ -20,10 +20,56 @@ type Config struct {
 		MaxIdleConns    int    `json:"maxIdleConns"`
 		MaxConnLifetime int    `json:"maxConnLifetime"`
 	} `json:"database"`
-	ServerPort string   `json:"serverPort"`
-	BaseURL    string   `json:"baseUrl"`
-	AllowedIPs []string `json:"allowedIPs"`
-	OpenAiToken string `json:"openAiToken"`
+	// tolerance for answer checking, expressed as edits per character of the expected answer.
+	// Fields left out use the defaults, 0 is a valid setting, e.g. correctRatio 0 only accepts exact answers.
+	Matching struct {
+		CorrectRatio *float64 `json:"correctRatio"`
+		CloseRatio   *float64 `json:"closeRatio"`
+		MinLength    *int     `json:"minLength"`
+	} `json:"matching"`
+	Sessions struct {
+		// sessions expire after this many hours without a request, defaults to 24
+		TTLHours int `json:"ttlHours"`
+	} `json:"sessions"`
+	Daily struct {
+		// a riddle is not picked again within this many days, defaults to 30
+		RepeatWindowDays int `json:"repeatWindowDays"`
+		// IANA time zone used when the client does not pass ?tz=, defaults to UTC
+		Timezone string `json:"timezone"`
+	} `json:"daily"`
+	Trash struct {
+		// deleted riddles are purged for good after this many days, defaults to 30
+		RetentionDays int `json:"retentionDays"`
+		// how often the purge job runs, defaults to 60
+		PurgeIntervalMinutes int `json:"purgeIntervalMinutes"`
+	} `json:"trash"`
+	Duplicates struct {
+		// trigram similarity from which a riddle with the same solution counts as a near duplicate, defaults to 0.6
+		Similarity float64 `json:"similarity"`
+	} `json:"duplicates"`
+	Concurrency struct {
+		// refuse updates and deletes of a riddle that do not send If-Match with 428
+		RequireIfMatch bool `json:"requireIfMatch"`
+	} `json:"concurrency"`
+	Replace struct {
+		// PUT on a riddle id that does not exist creates the riddle under that id instead of answering 404
+		Upsert bool `json:"upsert"`
+	} `json:"replace"`
+	Caching struct {
+		// Cache-Control of successful responses by route pattern, e.g. "/api/riddles/random": "no-store",
+		// replacing the defaults for the routes listed
+		Policies map[string]string `json:"policies"`
+	} `json:"caching"`
+	Versioning struct {
+		// date v1 was deprecated, YYYY-MM-DD, defaults to the release of v2
+		V1Deprecated string `json:"v1Deprecated"`
+		// date v1 stops being served, YYYY-MM-DD, the Sunset header is left out until it is set
+		V1Sunset string `json:"v1Sunset"`
+	} `json:"versioning"`
+	ServerPort  string   `json:"serverPort"`
+	BaseURL     string   `json:"baseUrl"`
+	AllowedIPs  []string `json:"allowedIPs"`
+	OpenAiToken string   `json:"openAiToken"`
 }
 
 var AppConfig Config
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+const dayLayout = "2006-01-02"
+
+// GetDailyRiddle returns the riddle for the given day, the scheduled or stored one, or else an automatic pick.
+// Automatic picks are deterministic and only stored when store is set, which the caller does for today alone,
+// so requests for other days never write and cannot change what a day gets.
+func GetDailyRiddle(day time.Time, store bool, repeatWindow int) (models.RiddleBase, bool, error) {
+	var rdl models.RiddleBase
+	dayString := day.Format(dayLayout)
+
+	var riddleID int
+	var scheduled, published bool
+	query := `SELECT d.riddle_id, d.scheduled, r.published AND r.deleted_at IS NULL
+		FROM daily_riddles d JOIN riddles r ON r.id = d.riddle_id
+		WHERE d.day = $1`
+	err := db.QueryRow(query, dayString).Scan(&riddleID, &scheduled, &published)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return rdl, false, err
+	}
+
+	if exists && published {
+		rdl, err = GetPublishedRiddle(riddleID)
+		return rdl, scheduled, err
+	}
+
+	riddleID, err = pickDailyRiddle(dayString, repeatWindow)
+	if err != nil {
+		return rdl, false, err
+	}
+
+	// an admin scheduled a riddle that is not published (anymore), serve the automatic pick
+	// without overwriting the schedule, so it comes back if the riddle is published again
+	if (exists && scheduled) || !store {
+		rdl, err = GetPublishedRiddle(riddleID)
+		return rdl, false, err
+	}
+
+	if exists {
+		_, err = db.Exec("UPDATE daily_riddles SET riddle_id = $2 WHERE day = $1 AND scheduled = FALSE", dayString, riddleID)
+	} else {
+		_, err = db.Exec("INSERT INTO daily_riddles (day, riddle_id) VALUES ($1, $2) ON CONFLICT (day) DO NOTHING", dayString, riddleID)
+	}
+	if err != nil {
+		return rdl, false, err
+	}
+
+	// another request may have stored its pick first, everybody gets the stored one
+	if err := db.QueryRow("SELECT riddle_id, scheduled FROM daily_riddles WHERE day = $1", dayString).Scan(&riddleID, &scheduled); err != nil {
+		return rdl, false, err
+	}
+
+	rdl, err = GetPublishedRiddle(riddleID)
+	return rdl, scheduled, err
+}
+
+// pickDailyRiddle orders the published riddles by a hash of their id and the day, so the pick is stable.
+// Riddles of the earlier days within the repeat window are skipped, and riddles scheduled for the later ones,
+// unless that leaves nothing to pick from. Earlier days only change while they are today, so the pick does not
+// depend on which days happened to be requested before.
+func pickDailyRiddle(day string, repeatWindow int) (int, error) {
+	var id int
+	query := `SELECT id FROM riddles
+		WHERE published = TRUE AND deleted_at IS NULL
+		AND id NOT IN (
+			SELECT riddle_id FROM daily_riddles
+			WHERE (day BETWEEN $1::date - $2::int AND $1::date - 1)
+			OR (scheduled AND day BETWEEN $1::date + 1 AND $1::date + $2::int)
+		)
+		ORDER BY md5(id::text || $1), id
+		LIMIT 1`
+	err := db.QueryRow(query, day, repeatWindow).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	query = "SELECT id FROM riddles WHERE published = TRUE AND deleted_at IS NULL ORDER BY md5(id::text || $1), id LIMIT 1"
+	err = db.QueryRow(query, day).Scan(&id)
+	return id, err
+}
+
+// ScheduleDailyRiddle lets an admin override the pick for a day
+func ScheduleDailyRiddle(day time.Time, riddleID int) error {
+	query := `INSERT INTO daily_riddles (day, riddle_id, scheduled) VALUES ($1, $2, TRUE)
+		ON CONFLICT (day) DO UPDATE SET riddle_id = EXCLUDED.riddle_id, scheduled = TRUE`
+	_, err := db.Exec(query, day.Format(dayLayout), riddleID)
+	return err
+}
+
+// UnscheduleDailyRiddle removes an admin override, the day gets an automatic pick on its next request
+func UnscheduleDailyRiddle(day time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM daily_riddles WHERE day = $1 AND scheduled = TRUE", day.Format(dayLayout))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -3,11 +3,12 @@ package db
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 
 	"github.com/ionutinit/riddles-api/models"
@@ -71,19 +72,66 @@ func GetDB() *sql.DB {
 	return db
 }
 
-func InsertNewRiddle(riddle models.Riddle) (int, error) {
-	query := "INSERT INTO riddles (riddle, solution, synonyms, username, user_email) VALUES ($1, $2, $3, $4, $5) RETURNING id"
-	var id int
-	err := db.QueryRow(query, riddle.Riddle, riddle.Solution, riddle.Synonyms, riddle.Username, riddle.UserEmail).Scan(&id)
+func InsertNewRiddle(riddle models.Riddle, editor string) (int, error) {
+	tx, err := db.Begin()
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
+
+	// submissions are never published directly, they wait in the review queue
+	id, err := insertRiddle(tx, 0, riddle, editor, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// insertRiddle stores the riddle with its tags and records the "create" revision, published riddles skip the review queue.
+// A zero id takes the next one from the sequence. A riddle whose normalized text is already in the catalog is refused with a DuplicateError.
+func insertRiddle(tx *sql.Tx, id int, riddle models.Riddle, editor string, publish bool) (int, error) {
+	if err := checkDuplicate(tx, riddle.Riddle, id); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO riddles (id, riddle, solution, synonyms, username, user_email, published, reviewed_by, reviewed_at)
+		VALUES (COALESCE(NULLIF($1, 0), nextval(pg_get_serial_sequence('riddles', 'id'))), $2, $3, $4, $5, $6, $7,
+			CASE WHEN $7 THEN $8 END, CASE WHEN $7 THEN NOW() END) RETURNING id`
+	err := tx.QueryRow(query, id, riddle.Riddle, riddle.Solution, pq.Array(riddle.Synonyms), riddle.Username, riddle.UserEmail, publish, editor).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := setRiddleTags(tx, id, riddle.Tags); err != nil {
+		return 0, err
+	}
+
+	if err := recordRevision(tx, id, "create", editor); err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
-func DeleteRiddle(id int) (int64, error) {
-	query := "DELETE FROM riddles WHERE id = $1"
-	result, err := db.Exec(query, id)
+// ErrVersionMismatch is returned when a riddle changed since the version the client based its change on
+var ErrVersionMismatch = errors.New("riddle was modified since the given version")
+
+// versionCondition restricts a statement to the expected versions of the riddle, nil versions place no condition
+func versionCondition(versions []int, args *[]interface{}) string {
+	if versions == nil {
+		return ""
+	}
+	*args = append(*args, pq.Array(versions))
+	return fmt.Sprintf(" AND version = ANY($%d)", len(*args))
+}
+
+// DeleteRiddle moves a riddle to the trash, PurgeDeletedRiddles removes it for good after the retention period.
+// With expected versions the riddle is only deleted if it is still at one of them, otherwise ErrVersionMismatch is returned.
+func DeleteRiddle(id int, expected []int) (int64, error) {
+	args := []interface{}{id}
+	query := "UPDATE riddles SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL" + versionCondition(expected, &args)
+	result, err := db.Exec(query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -93,62 +141,99 @@ func DeleteRiddle(id int) (int64, error) {
 		return 0, err
 	}
 
+	if rowsAffected == 0 && expected != nil {
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM riddles WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, ErrVersionMismatch
+		}
+	}
+
 	return rowsAffected, nil
 }
 
-func UpdateRiddle(id int, riddle models.Riddle) error {
-	query := "UPDATE riddles SET "
-	args := []interface{}{}
-	argID := 1
-
-	if riddle.Riddle != "" {
-		query += fmt.Sprintf("riddle = $%d, ", argID)
-		args = append(args, riddle.Riddle)
-		argID++
+// UpdateRiddle writes the whole riddle, so empty synonyms, username and user_email clear the stored values.
+// With expected versions the riddle is only updated if it is still at one of them, otherwise ErrVersionMismatch is returned.
+func UpdateRiddle(id int, riddle models.Riddle, editor string, expected []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	if riddle.Solution != "" {
-		query += fmt.Sprintf("solution = $%d, ", argID)
-		args = append(args, riddle.Solution)
-		argID++
+	if err := updateRiddle(tx, id, riddle, editor, expected); err != nil {
+		return err
 	}
 
-	if riddle.Synonyms != nil {
-		query += fmt.Sprintf("synonyms = $%d, ", argID)
-		args = append(args, riddle.Synonyms)
-		argID++
-	}
+	return tx.Commit()
+}
 
-	if riddle.Username.Valid {
-		query += fmt.Sprintf("username = $%d, ", argID)
-		args = append(args, riddle.Username)
-		argID++
+func updateRiddle(tx *sql.Tx, id int, riddle models.Riddle, editor string, expected []int) error {
+	if err := lockRiddle(tx, id); err != nil {
+		return err
 	}
 
-	if riddle.UserEmail.Valid {
-		query += fmt.Sprintf("user_email = $%d, ", argID)
-		args = append(args, riddle.UserEmail)
-		argID++
+	if err := checkChangedText(tx, id, riddle.Riddle); err != nil {
+		return err
 	}
 
-	if len(args) == 0 {
-		return fmt.Errorf("no fields to update")
+	if err := recordBaseline(tx, id); err != nil {
+		return err
 	}
 
-	query = query[:len(query)-2] + fmt.Sprintf(" WHERE id = $%d", argID)
-	args = append(args, id)
+	var synonyms interface{}
+	if len(riddle.Synonyms) > 0 {
+		synonyms = pq.Array(riddle.Synonyms)
+	}
 
-	// log.Printf("Executing query: %s with args: %v\n", query, args)
+	// the version is checked by the update itself, so two editors starting from the same version cannot both succeed
+	args := []interface{}{riddle.Riddle, riddle.Solution, synonyms, riddle.Username, riddle.UserEmail, id}
+	query := "UPDATE riddles SET riddle = $1, solution = $2, synonyms = $3, username = $4, user_email = $5 WHERE id = $6" +
+		versionCondition(expected, &args)
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":    id,
+			"error": err,
+		}).Error("Error executing update query")
+		return err
+	}
 
-	_, err := db.Exec(query, args...)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+
+	if err := setRiddleTags(tx, id, riddle.Tags); err != nil {
 		logger.Log.WithFields(logrus.Fields{
 			"id":    id,
 			"error": err,
-			"query": query,
-			"args":  args,
-		}).Error("Error executing patch query")
+			"tags":  riddle.Tags,
+		}).Error("Error updating riddle tags")
 		return err
 	}
-	return nil
+
+	return recordRevision(tx, id, "update", editor)
+}
+
+func GetPublishedRiddle(id int) (models.RiddleBase, error) {
+	var rdl models.RiddleBase
+	query := `SELECT id, riddle, solution, synonyms, version, COALESCE(last_modified, 'epoch'::timestamp) FROM riddles
+		WHERE id = $1 AND published = TRUE AND deleted_at IS NULL`
+	err := db.QueryRow(query, id).Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms), &rdl.Version, &rdl.LastModified)
+	return rdl, err
+}
+
+// RandomRiddle picks a random published riddle matching the filter
+func RandomRiddle(filter RiddleFilter) (models.RiddleBase, error) {
+	var rdl models.RiddleBase
+	args := queryArgs{}
+	query := "SELECT id, riddle, solution, synonyms FROM riddles WHERE " + filter.where(&args) + " ORDER BY RANDOM() LIMIT 1"
+	err := db.QueryRow(query, args...).Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms))
+	return rdl, err
 }
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// DuplicateError is returned when a riddle with the same normalized text already exists
+type DuplicateError struct {
+	ID int
+	// Published tells whether the existing riddle is public, pending and rejected riddles cannot be linked to
+	Published bool
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate of riddle %d", e.ID)
+}
+
+// checkDuplicate looks for another riddle than excludeID with the same fingerprint, the advisory lock keeps two
+// concurrent submissions of the same riddle from both getting through
+func checkDuplicate(tx *sql.Tx, riddle string, excludeID int) error {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(riddle_fingerprint($1)))", riddle); err != nil {
+		return err
+	}
+
+	// a published duplicate is preferred, it is the one the submitter can be pointed to
+	var duplicate DuplicateError
+	err := tx.QueryRow(`SELECT id, published FROM riddles WHERE riddle_fingerprint(riddle) = riddle_fingerprint($1) AND deleted_at IS NULL
+		AND id <> $2 ORDER BY published DESC, id LIMIT 1`, riddle, excludeID).Scan(&duplicate.ID, &duplicate.Published)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &duplicate
+}
+
+// checkChangedText runs checkDuplicate when a change gives the riddle a new text, the riddle must be locked
+func checkChangedText(tx *sql.Tx, id int, riddle string) error {
+	var current string
+	if err := tx.QueryRow("SELECT riddle FROM riddles WHERE id = $1", id).Scan(&current); err != nil {
+		return err
+	}
+	if current == riddle {
+		return nil
+	}
+	return checkDuplicate(tx, riddle, id)
+}
+
+// setSimilarityThreshold makes the % operator, which can use the trigram index, match the threshold for this transaction only
+func setSimilarityThreshold(tx *sql.Tx, threshold float64) error {
+	_, err := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', $1, true)", strconv.FormatFloat(threshold, 'f', -1, 64))
+	return err
+}
+
+// SimilarRiddles returns the near duplicates of a riddle, those with similar text and the same solution, most similar first
+func SimilarRiddles(riddle string, solution string, excludeID int, threshold float64) ([]models.DuplicateWarning, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := setSimilarityThreshold(tx, threshold); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, riddle, published, similarity(riddle, $1) AS score FROM riddles
+		WHERE riddle % $1 AND riddle_fingerprint(solution) = riddle_fingerprint($2) AND id <> $3 AND deleted_at IS NULL
+		ORDER BY score DESC, id LIMIT 10`
+	rows, err := tx.Query(query, riddle, solution, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warnings []models.DuplicateWarning
+	for rows.Next() {
+		var s models.DuplicateWarning
+		if err := rows.Scan(&s.ID, &s.Riddle, &s.Published, &s.Similarity); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, s)
+	}
+	return warnings, rows.Err()
+}
+
+type duplicatePair struct {
+	a, b  int
+	exact bool
+}
+
+// DuplicateClusters groups the riddles in the catalog that duplicate each other, either exactly or
+// as near duplicates. Pairs are joined transitively, so a cluster can hold riddles that only resemble each other through a third one.
+func DuplicateClusters(threshold float64) ([]models.DuplicateCluster, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := setSimilarityThreshold(tx, threshold); err != nil {
+		return nil, err
+	}
+
+	// exact and near duplicates are looked up per riddle, through the fingerprint and the trigram index,
+	// instead of comparing every pair of riddles. A pair found both ways is exact.
+	query := `SELECT a, b, bool_or(exact) FROM (
+			SELECT a.id AS a, b.id AS b, TRUE AS exact
+			FROM riddles a CROSS JOIN LATERAL (
+				SELECT id FROM riddles b
+				WHERE riddle_fingerprint(b.riddle) = riddle_fingerprint(a.riddle) AND b.deleted_at IS NULL AND b.id > a.id
+			) b
+			WHERE a.deleted_at IS NULL
+			UNION ALL
+			SELECT a.id, b.id, FALSE
+			FROM riddles a CROSS JOIN LATERAL (
+				SELECT id FROM riddles b
+				WHERE b.riddle % a.riddle AND b.deleted_at IS NULL AND b.id > a.id
+				AND riddle_fingerprint(b.solution) = riddle_fingerprint(a.solution)
+			) b
+			WHERE a.deleted_at IS NULL
+		) pairs
+		GROUP BY a, b`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []duplicatePair
+	for rows.Next() {
+		var p duplicatePair
+		if err := rows.Scan(&p.a, &p.b, &p.exact); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// union-find over the pairs, each root ends up naming one cluster
+	parent := map[int]int{}
+	var find func(id int) int
+	find = func(id int) int {
+		if _, ok := parent[id]; !ok {
+			parent[id] = id
+		}
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for _, p := range pairs {
+		rootA, rootB := find(p.a), find(p.b)
+		if rootA != rootB {
+			parent[rootB] = rootA
+		}
+	}
+
+	// a cluster is exact only when every pair in it is
+	exact := map[int]bool{}
+	for _, p := range pairs {
+		root := find(p.a)
+		if current, seen := exact[root]; seen {
+			exact[root] = current && p.exact
+		} else {
+			exact[root] = p.exact
+		}
+	}
+
+	ids := make([]int, 0, len(parent))
+	for id := range parent {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err = tx.Query("SELECT "+adminRiddleColumns+" FROM riddles WHERE id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clusters := map[int]*models.DuplicateCluster{}
+	var roots []int
+	for rows.Next() {
+		rdl, err := scanAdminRiddle(rows)
+		if err != nil {
+			return nil, err
+		}
+		root := find(rdl.ID)
+		cluster, ok := clusters[root]
+		if !ok {
+			cluster = &models.DuplicateCluster{Exact: exact[root]}
+			clusters[root] = cluster
+			roots = append(roots, root)
+		}
+		cluster.Riddles = append(cluster.Riddles, rdl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// biggest clusters first, then by their oldest riddle
+	result := make([]models.DuplicateCluster, 0, len(roots))
+	for _, root := range roots {
+		result = append(result, *clusters[root])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i].Riddles) > len(result[j].Riddles)
+	})
+	return result, nil
+}
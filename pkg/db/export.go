@@ -0,0 +1,35 @@
+package db
+
+import (
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// ExportRiddles streams the riddles matching the filter in id order, handing each one to emit as soon as it is read.
+// Nothing is held in memory, so the whole catalog can be exported. An error returned by emit stops the export.
+func ExportRiddles(filter RiddleFilter, emit func(models.AdminRiddle) error) error {
+	args := queryArgs{}
+	query := "SELECT " + adminRiddleColumns + `,
+		ARRAY(SELECT t.name FROM riddle_tags rt JOIN tags t ON t.id = rt.tag_id WHERE rt.riddle_id = riddles.id ORDER BY t.name)
+		FROM riddles WHERE ` + filter.where(&args) + " ORDER BY id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rdl models.AdminRiddle
+		err := rows.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms), &rdl.Username, &rdl.UserEmail, &rdl.Published,
+			&rdl.DateCreated, &rdl.ReviewedBy, &rdl.ReviewedAt, &rdl.RejectionReason, &rdl.DeletedAt, &rdl.Version, pq.Array(&rdl.Tags))
+		if err != nil {
+			return err
+		}
+		if err := emit(rdl); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
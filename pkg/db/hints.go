@@ -0,0 +1,135 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+func RiddleExists(id int) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM riddles WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	return exists, err
+}
+
+func GetHints(riddleID int) ([]models.Hint, error) {
+	rows, err := db.Query("SELECT id, riddle_id, position, hint FROM hints WHERE riddle_id = $1 ORDER BY position", riddleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hints []models.Hint
+	for rows.Next() {
+		var hint models.Hint
+		if err := rows.Scan(&hint.ID, &hint.RiddleID, &hint.Position, &hint.Hint); err != nil {
+			return nil, err
+		}
+		hints = append(hints, hint)
+	}
+	return hints, rows.Err()
+}
+
+// InsertHint adds a hint at the given 1-based position, shifting later hints down
+// a position of 0 or past the end appends the hint
+func InsertHint(riddleID int, text string, position int) (models.Hint, error) {
+	hint := models.Hint{RiddleID: riddleID, Hint: text}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return hint, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM hints WHERE riddle_id = $1", riddleID).Scan(&count); err != nil {
+		return hint, err
+	}
+
+	if position <= 0 || position > count {
+		position = count + 1
+	}
+
+	if _, err := tx.Exec("UPDATE hints SET position = position + 1 WHERE riddle_id = $1 AND position >= $2", riddleID, position); err != nil {
+		return hint, err
+	}
+
+	query := "INSERT INTO hints (riddle_id, position, hint) VALUES ($1, $2, $3) RETURNING id"
+	if err := tx.QueryRow(query, riddleID, position, text).Scan(&hint.ID); err != nil {
+		return hint, err
+	}
+	hint.Position = position
+
+	return hint, tx.Commit()
+}
+
+// ErrInvalidHintOrder is returned when a reorder request is not a permutation of the current positions
+var ErrInvalidHintOrder = errors.New("invalid hint order")
+
+// ReorderHints takes the current positions in their new order, e.g. [3, 1, 2] moves the third hint first
+func ReorderHints(riddleID int, order []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM hints WHERE riddle_id = $1", riddleID).Scan(&count); err != nil {
+		return err
+	}
+
+	if len(order) != count {
+		return fmt.Errorf("%w: order must list all %d hints", ErrInvalidHintOrder, count)
+	}
+
+	seen := make(map[int]bool, len(order))
+	for _, position := range order {
+		if position < 1 || position > count || seen[position] {
+			return fmt.Errorf("%w: order must be a permutation of positions 1 to %d", ErrInvalidHintOrder, count)
+		}
+		seen[position] = true
+	}
+
+	// the unique constraint on positions is deferred, so intermediate duplicates are fine
+	ids := make([]int, len(order))
+	for i, position := range order {
+		if err := tx.QueryRow("SELECT id FROM hints WHERE riddle_id = $1 AND position = $2", riddleID, position).Scan(&ids[i]); err != nil {
+			return err
+		}
+	}
+
+	for i, id := range ids {
+		if _, err := tx.Exec("UPDATE hints SET position = $1 WHERE id = $2", i+1, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteHint removes the hint at the given position and closes the gap it leaves
+func DeleteHint(riddleID int, position int) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM hints WHERE riddle_id = $1 AND position = $2", riddleID, position)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE hints SET position = position - 1 WHERE riddle_id = $1 AND position > $2", riddleID, position); err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, tx.Commit()
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// ImportError tells which of the imported riddles could not be stored
+type ImportError struct {
+	Row int
+	Err error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateRowError is found by CheckImport for a riddle repeating an earlier one of the same import
+type DuplicateRowError struct {
+	Row int
+}
+
+func (e *DuplicateRowError) Error() string {
+	return fmt.Sprintf("duplicate of row %d", e.Row)
+}
+
+// CheckImport tells which riddles InsertRiddles would refuse as duplicates, without storing any of them: those already
+// in the catalog and those repeating an earlier riddle of the import. The result has the error of every riddle, nil when it would be stored.
+func CheckImport(riddles []models.Riddle) ([]error, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// nothing is written, the transaction only holds the duplicate locks while checking
+	defer tx.Rollback()
+
+	errs := make([]error, len(riddles))
+	seen := map[string]int{}
+	for i, riddle := range riddles {
+		err := checkDuplicate(tx, riddle.Riddle, 0)
+		var duplicate *DuplicateError
+		if errors.As(err, &duplicate) {
+			errs[i] = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var fingerprint string
+		if err := tx.QueryRow("SELECT riddle_fingerprint($1)", riddle.Riddle).Scan(&fingerprint); err != nil {
+			return nil, err
+		}
+		if row, ok := seen[fingerprint]; ok {
+			errs[i] = &DuplicateRowError{Row: row}
+			continue
+		}
+		seen[fingerprint] = i
+	}
+	return errs, nil
+}
+
+// InsertRiddles stores all riddles in a single transaction, either every one of them is created or none is.
+// Imported riddles are published straight away when publish is set, otherwise they join the review queue.
+func InsertRiddles(riddles []models.Riddle, editor string, publish bool) ([]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, 0, len(riddles))
+	for i, riddle := range riddles {
+		id, err := insertRiddle(tx, 0, riddle, editor, publish)
+		if err != nil {
+			return nil, &ImportError{Row: i, Err: err}
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, tx.Commit()
+}
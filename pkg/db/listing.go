@@ -0,0 +1,263 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// Cursor marks a position in the listing, the sort key of a riddle plus its id to break ties
+type Cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Key  string `json:"k"`
+	ID   int    `json:"id"`
+	// Backward cursors fetch the page before the position instead of the page after it
+	Backward bool `json:"b,omitempty"`
+}
+
+// RiddleFilter narrows the listing, zero values mean no filtering
+type RiddleFilter struct {
+	Username      string
+	CreatedFrom   *time.Time
+	CreatedBefore *time.Time
+	ModifiedSince *time.Time
+	MinSolution   int
+	MaxSolution   int
+	HasImages     *bool
+	Tag           string
+	// IncludeUnpublished also matches riddles waiting in or rejected by the review queue, for admins only
+	IncludeUnpublished bool
+}
+
+type ListParams struct {
+	Limit  int
+	Cursor *Cursor
+	Filter RiddleFilter
+	Sort   string
+	Desc   bool
+}
+
+type RiddlePage struct {
+	Riddles []models.RiddleBase
+	Total   int
+	Next    *Cursor
+	Prev    *Cursor
+}
+
+type sortKey struct {
+	expression string
+	// cast applied to the cursor key, which travels as text
+	cast string
+}
+
+// sortKeys whitelists the sortable columns, user input only ever selects one of these
+var sortKeys = map[string]sortKey{
+	"id":         {expression: "id", cast: "int"},
+	"created":    {expression: "COALESCE(date_created, 'epoch'::timestamp)", cast: "timestamp"},
+	"modified":   {expression: "COALESCE(last_modified, 'epoch'::timestamp)", cast: "timestamp"},
+	"popularity": {expression: "COALESCE((SELECT guesses FROM riddle_stats WHERE riddle_stats.riddle_id = riddles.id), 0)", cast: "int"},
+}
+
+// timestampText is how Postgres writes a timestamp cast to text, the form cursor keys of dates come in
+const timestampText = "2006-01-02 15:04:05.999999"
+
+// ValidCursor reports whether the cursor can be used with its sort, its key must parse as the type the key is cast to,
+// so that a cursor that was tampered with is refused instead of failing in the query
+func ValidCursor(cursor *Cursor) bool {
+	key, ok := sortKeys[cursor.Sort]
+	if !ok || cursor.ID < 0 {
+		return false
+	}
+
+	switch key.cast {
+	case "int":
+		_, err := strconv.ParseInt(cursor.Key, 10, 32)
+		return err == nil
+	case "timestamp":
+		_, err := time.Parse(timestampText, cursor.Key)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// DefaultSort orders the listing by creation date, with the id breaking ties between riddles created together
+const DefaultSort = "created"
+
+func IsSortKey(sort string) bool {
+	_, ok := sortKeys[sort]
+	return ok
+}
+
+// queryArgs numbers the placeholders as values are added
+type queryArgs []interface{}
+
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// where builds the parameterized conditions for the filter
+func (f RiddleFilter) where(args *queryArgs) string {
+	where := "published = TRUE AND deleted_at IS NULL"
+	if f.IncludeUnpublished {
+		where = "deleted_at IS NULL"
+	}
+
+	if f.Username != "" {
+		where += " AND username = " + args.add(f.Username)
+	}
+	if f.CreatedFrom != nil {
+		where += " AND date_created >= " + args.add(*f.CreatedFrom)
+	}
+	if f.CreatedBefore != nil {
+		where += " AND date_created < " + args.add(*f.CreatedBefore)
+	}
+	if f.ModifiedSince != nil {
+		where += " AND last_modified >= " + args.add(*f.ModifiedSince)
+	}
+	if f.MinSolution > 0 {
+		where += " AND char_length(solution) >= " + args.add(f.MinSolution)
+	}
+	if f.MaxSolution > 0 {
+		where += " AND char_length(solution) <= " + args.add(f.MaxSolution)
+	}
+	if f.HasImages != nil {
+		images := "EXISTS (SELECT 1 FROM images WHERE images.riddleId = riddles.id)"
+		if !*f.HasImages {
+			images = "NOT " + images
+		}
+		where += " AND " + images
+	}
+	if f.Tag != "" {
+		where += ` AND EXISTS (SELECT 1 FROM riddle_tags rt JOIN tags t ON t.id = rt.tag_id
+			WHERE rt.riddle_id = riddles.id AND t.name = ` + args.add(f.Tag) + ")"
+	}
+
+	return where
+}
+
+// Freshness sums up the state of the whole catalog, any change to a riddle moves LastModified
+// and purged riddles lower the count, so listings can be validated without running them
+type Freshness struct {
+	LastModified time.Time
+	Riddles      int
+	// guesses only matter to listings sorted by popularity
+	Guesses int
+	// images only matter to listings filtered by has_images, a new image raises the last id and a deleted one lowers the count
+	Images    int
+	LastImage int
+}
+
+func ListingFreshness(withGuesses bool, withImages bool) (Freshness, error) {
+	var f Freshness
+	guesses := "0"
+	if withGuesses {
+		guesses = "(SELECT COALESCE(SUM(guesses), 0) FROM riddle_stats)"
+	}
+	images := "0, 0"
+	if withImages {
+		images = "(SELECT COUNT(*) FROM images), (SELECT COALESCE(MAX(id), 0) FROM images)"
+	}
+	query := "SELECT COALESCE(MAX(last_modified), 'epoch'::timestamp), COUNT(*), " + guesses + ", " + images + " FROM riddles"
+	err := db.QueryRow(query).Scan(&f.LastModified, &f.Riddles, &f.Guesses, &f.Images, &f.LastImage)
+	return f, err
+}
+
+// ListRiddles returns one page of published riddles using keyset pagination
+func ListRiddles(params ListParams) (RiddlePage, error) {
+	var page RiddlePage
+
+	if params.Sort == "" {
+		params.Sort = DefaultSort
+	}
+	key, ok := sortKeys[params.Sort]
+	if !ok {
+		return page, fmt.Errorf("unknown sort key: %s", params.Sort)
+	}
+
+	args := queryArgs{}
+	where := params.Filter.where(&args)
+	if err := db.QueryRow("SELECT COUNT(*) FROM riddles WHERE "+where, args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+
+	backward := params.Cursor != nil && params.Cursor.Backward
+	// walking backwards is walking forwards in the opposite order
+	ascending := params.Desc == backward
+	comparison, direction := ">", "ASC"
+	if !ascending {
+		comparison, direction = "<", "DESC"
+	}
+
+	if params.Cursor != nil {
+		where += fmt.Sprintf(" AND (%s, id) %s (%s::%s, %s)",
+			key.expression, comparison, args.add(params.Cursor.Key), key.cast, args.add(params.Cursor.ID))
+	}
+
+	// one extra row tells whether there is another page in the direction we are going
+	query := fmt.Sprintf("SELECT id, riddle, solution, synonyms, %s::text FROM riddles WHERE %s ORDER BY %s %s, id %s LIMIT %s",
+		key.expression, where, key.expression, direction, direction, args.add(params.Limit+1))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var rdl models.RiddleBase
+		var key string
+		if err := rows.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms), &key); err != nil {
+			return page, err
+		}
+		page.Riddles = append(page.Riddles, rdl)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	hasMore := len(page.Riddles) > params.Limit
+	if hasMore {
+		page.Riddles = page.Riddles[:params.Limit]
+		keys = keys[:params.Limit]
+	}
+
+	if backward {
+		for i, j := 0, len(page.Riddles)-1; i < j; i, j = i+1, j-1 {
+			page.Riddles[i], page.Riddles[j] = page.Riddles[j], page.Riddles[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	if len(page.Riddles) == 0 {
+		return page, nil
+	}
+
+	first := &Cursor{Sort: params.Sort, Desc: params.Desc, Key: keys[0], ID: page.Riddles[0].ID, Backward: true}
+	last := &Cursor{Sort: params.Sort, Desc: params.Desc, Key: keys[len(keys)-1], ID: page.Riddles[len(page.Riddles)-1].ID}
+
+	// going forward there is a previous page whenever we started from a cursor, and the other way round
+	if backward {
+		page.Next = last
+		if hasMore {
+			page.Prev = first
+		}
+	} else {
+		if hasMore {
+			page.Next = last
+		}
+		if params.Cursor != nil {
+			page.Prev = first
+		}
+	}
+
+	return page, nil
+}
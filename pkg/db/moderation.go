@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+const adminRiddleColumns = `id, riddle, solution, synonyms, username, user_email, published,
+	COALESCE(date_created, 'epoch'::timestamp), reviewed_by, reviewed_at, rejection_reason, deleted_at, version`
+
+func scanAdminRiddle(scanner interface{ Scan(...interface{}) error }) (models.AdminRiddle, error) {
+	var rdl models.AdminRiddle
+	err := scanner.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms), &rdl.Username, &rdl.UserEmail, &rdl.Published,
+		&rdl.DateCreated, &rdl.ReviewedBy, &rdl.ReviewedAt, &rdl.RejectionReason, &rdl.DeletedAt, &rdl.Version)
+	return rdl, err
+}
+
+// ListPendingRiddles returns the review queue, oldest submissions first
+func ListPendingRiddles() ([]models.AdminRiddle, error) {
+	return listAdminRiddles("WHERE published = FALSE AND reviewed_at IS NULL AND deleted_at IS NULL ORDER BY date_created, id")
+}
+
+// GetAdminRiddle returns the full view of a riddle that is not in the trash, published or not
+func GetAdminRiddle(id int) (models.AdminRiddle, error) {
+	row := db.QueryRow("SELECT "+adminRiddleColumns+" FROM riddles WHERE id = $1 AND deleted_at IS NULL", id)
+	rdl, err := scanAdminRiddle(row)
+	if err != nil {
+		return rdl, err
+	}
+
+	tags, err := TagsForRiddles([]int{id})
+	if err != nil {
+		return rdl, err
+	}
+	rdl.Tags = tags[id]
+	return rdl, nil
+}
+
+func listAdminRiddles(condition string) ([]models.AdminRiddle, error) {
+	rows, err := db.Query("SELECT " + adminRiddleColumns + " FROM riddles " + condition)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var riddles []models.AdminRiddle
+	for rows.Next() {
+		rdl, err := scanAdminRiddle(rows)
+		if err != nil {
+			return nil, err
+		}
+		riddles = append(riddles, rdl)
+	}
+	return riddles, rows.Err()
+}
+
+// ErrAlreadyReviewed is returned when reviewing a riddle that left the review queue, published or rejected
+var ErrAlreadyReviewed = errors.New("riddle was already reviewed")
+
+// ReviewRiddle publishes or rejects a riddle waiting for review, recording who reviewed it and when
+// a rejection reason is only kept for rejections. A riddle that was already reviewed is left as it is, with ErrAlreadyReviewed.
+func ReviewRiddle(id int, approve bool, reviewer string, reason string) (int64, error) {
+	var rejectionReason *string
+	if !approve {
+		rejectionReason = &reason
+	}
+
+	query := `UPDATE riddles SET published = $2, reviewed_by = $3, reviewed_at = NOW(), rejection_reason = $4
+		WHERE id = $1 AND published = FALSE AND reviewed_at IS NULL AND deleted_at IS NULL`
+	result, err := db.Exec(query, id, approve, reviewer, rejectionReason)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected > 0 {
+		return rowsAffected, err
+	}
+
+	// nothing pending under that id, tell a reviewed riddle apart from a missing one
+	var exists bool
+	err = db.QueryRow("SELECT TRUE FROM riddles WHERE id = $1 AND deleted_at IS NULL", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, ErrAlreadyReviewed
+}
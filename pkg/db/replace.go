@@ -0,0 +1,79 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// ErrRiddleInTrash is returned when replacing a riddle that was deleted, it has to be restored first
+var ErrRiddleInTrash = errors.New("riddle is in the trash")
+
+// ErrInvalidRiddleID is returned when creating a riddle under an id the sequence could never have handed out
+var ErrInvalidRiddleID = errors.New("riddle ids start at 1")
+
+// ReplaceRiddle writes the whole riddle like UpdateRiddle. With upsert, a riddle that does not exist is created under
+// that id instead, published straight away when publish is set. The bool reports whether the riddle was created.
+// Expected versions only ever match an existing riddle, so they never create one.
+func ReplaceRiddle(id int, riddle models.Riddle, editor string, expected []int, upsert bool, publish bool) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = updateRiddle(tx, id, riddle, editor, expected)
+	if err == sql.ErrNoRows && upsert {
+		if expected != nil {
+			return false, ErrVersionMismatch
+		}
+		created, err := createRiddle(tx, id, riddle, editor, publish)
+		if err != nil {
+			return false, err
+		}
+		return created, tx.Commit()
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, tx.Commit()
+}
+
+// createRiddle inserts the riddle under the given id. Concurrent upserts of the same id wait for each other,
+// the later one then replaces what the first created. An id below 1 is refused, insertRiddle would take the next one of the sequence instead.
+func createRiddle(tx *sql.Tx, id int, riddle models.Riddle, editor string, publish bool) (bool, error) {
+	if id < 1 {
+		return false, ErrInvalidRiddleID
+	}
+
+	// the two argument form keeps these locks apart from the fingerprint ones
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(1, $1)", id); err != nil {
+		return false, err
+	}
+
+	var deleted bool
+	err := tx.QueryRow("SELECT deleted_at IS NOT NULL FROM riddles WHERE id = $1", id).Scan(&deleted)
+	switch {
+	case err == nil && deleted:
+		return false, ErrRiddleInTrash
+	case err == nil:
+		return false, updateRiddle(tx, id, riddle, editor, nil)
+	case err != sql.ErrNoRows:
+		return false, err
+	}
+
+	if _, err := insertRiddle(tx, id, riddle, editor, publish); err != nil {
+		return false, err
+	}
+
+	// later riddles must not be handed an id that was taken here
+	query := `SELECT setval(seq::regclass, GREATEST($1, COALESCE(pg_sequence_last_value(seq::regclass), 1)))
+		FROM pg_get_serial_sequence('riddles', 'id') seq`
+	if _, err := tx.Exec(query, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
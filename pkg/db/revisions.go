@@ -0,0 +1,125 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// lockRiddle serializes changes to a riddle for the rest of the transaction, so revision numbers never collide
+func lockRiddle(tx *sql.Tx, riddleID int) error {
+	var id int
+	return tx.QueryRow("SELECT id FROM riddles WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", riddleID).Scan(&id)
+}
+
+// missingRiddle tells why lockRiddle found no riddle, ErrRiddleInTrash when it was deleted and sql.ErrNoRows when there is none
+func missingRiddle(tx *sql.Tx, riddleID int) error {
+	var deleted bool
+	if err := tx.QueryRow("SELECT deleted_at IS NOT NULL FROM riddles WHERE id = $1", riddleID).Scan(&deleted); err != nil {
+		return err
+	}
+	if deleted {
+		return ErrRiddleInTrash
+	}
+	return sql.ErrNoRows
+}
+
+// recordRevision snapshots the current state of the riddle, tags included, an empty editor is recorded as unknown
+func recordRevision(tx *sql.Tx, riddleID int, changeType string, editor string) error {
+	query := `INSERT INTO riddle_revisions (riddle_id, revision, riddle, solution, synonyms, username, user_email, tags, change_type, changed_by)
+		SELECT r.id,
+			COALESCE((SELECT MAX(revision) FROM riddle_revisions WHERE riddle_id = r.id), 0) + 1,
+			r.riddle, r.solution, r.synonyms, r.username, r.user_email,
+			ARRAY(SELECT t.name FROM riddle_tags rt JOIN tags t ON t.id = rt.tag_id WHERE rt.riddle_id = r.id ORDER BY t.name),
+			$2, NULLIF($3, '')
+		FROM riddles r WHERE r.id = $1`
+	_, err := tx.Exec(query, riddleID, changeType, editor)
+	return err
+}
+
+// recordBaseline keeps the state from before revisions were tracked, so the first tracked change can be reverted too
+func recordBaseline(tx *sql.Tx, riddleID int) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM riddle_revisions WHERE riddle_id = $1)", riddleID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return recordRevision(tx, riddleID, "baseline", "")
+}
+
+const revisionColumns = "revision, riddle, solution, synonyms, username, user_email, tags, change_type, changed_by, COALESCE(date_created, 'epoch'::timestamp)"
+
+func scanRevision(scanner interface{ Scan(...interface{}) error }) (models.Revision, error) {
+	var rev models.Revision
+	err := scanner.Scan(&rev.Revision, &rev.Riddle, &rev.Solution, pq.Array(&rev.Synonyms), &rev.Username, &rev.UserEmail,
+		pq.Array(&rev.Tags), &rev.ChangeType, &rev.ChangedBy, &rev.DateCreated)
+	return rev, err
+}
+
+func ListRevisions(riddleID int) ([]models.Revision, error) {
+	rows, err := db.Query("SELECT "+revisionColumns+" FROM riddle_revisions WHERE riddle_id = $1 ORDER BY revision", riddleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func GetRevision(riddleID int, revision int) (models.Revision, error) {
+	row := db.QueryRow("SELECT "+revisionColumns+" FROM riddle_revisions WHERE riddle_id = $1 AND revision = $2", riddleID, revision)
+	return scanRevision(row)
+}
+
+// RevertRiddle restores the content of an earlier revision, recorded as a new revision
+func RevertRiddle(riddleID int, revision int, editor string) error {
+	rev, err := GetRevision(riddleID, revision)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockRiddle(tx, riddleID); err != nil {
+		if err == sql.ErrNoRows {
+			return missingRiddle(tx, riddleID)
+		}
+		return err
+	}
+
+	// another riddle may have taken the old text since
+	if err := checkChangedText(tx, riddleID, rev.Riddle); err != nil {
+		return err
+	}
+
+	query := "UPDATE riddles SET riddle = $2, solution = $3, synonyms = $4, username = $5, user_email = $6 WHERE id = $1"
+	if _, err := tx.Exec(query, riddleID, rev.Riddle, rev.Solution, pq.Array(rev.Synonyms), rev.Username, rev.UserEmail); err != nil {
+		return err
+	}
+
+	if err := setRiddleTags(tx, riddleID, rev.Tags); err != nil {
+		return err
+	}
+
+	if err := recordRevision(tx, riddleID, "revert", editor); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+var searchTerm = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchQuery turns free text into a prefix matching tsquery, e.g. "candle wa" becomes "candle:* & wa:*"
+// only letters and digits survive, so the result is always valid tsquery syntax
+// excluding solutions restricts every term to the riddle text, which is weighted A
+func SearchQuery(text string, excludeSolutions bool) string {
+	label := ":*"
+	if excludeSolutions {
+		label = ":*A"
+	}
+
+	var terms []string
+	for _, term := range searchTerm.FindAllString(strings.ToLower(text), -1) {
+		terms = append(terms, term+label)
+	}
+	return strings.Join(terms, " & ")
+}
+
+// ts_headline marks the matches with control characters, which are taken out of the riddle first,
+// so that the text can be escaped before the matches are wrapped in <mark></mark>
+const (
+	markStart = "\x02"
+	markStop  = "\x03"
+)
+
+var snippetMarks = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+
+// highlightSnippet escapes the riddle text of a headline and turns its marks into <mark></mark>, riddles are user
+// submitted and the snippet is meant to be rendered as HTML
+func highlightSnippet(headline string) string {
+	return snippetMarks.Replace(html.EscapeString(headline))
+}
+
+// SearchRiddles ranks published riddles against a query built by SearchQuery
+func SearchRiddles(tsquery string, limit int) ([]models.SearchResult, error) {
+	query := `SELECT id, riddle, solution, synonyms, ts_rank(search_vector, q) AS rank,
+			ts_headline('english', translate(riddle, chr(2) || chr(3), ''), q,
+				'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2')
+		FROM riddles, to_tsquery('english', $1) q
+		WHERE published = TRUE AND deleted_at IS NULL AND search_vector @@ q
+		ORDER BY rank DESC, id
+		LIMIT $2`
+
+	rows, err := db.Query(query, tsquery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var result models.SearchResult
+		rdl := &result.RiddleBase
+		if err := rows.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms), &result.Rank, &result.Snippet); err != nil {
+			return nil, err
+		}
+		result.Snippet = highlightSnippet(result.Snippet)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,40 @@
+package db
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{"I am tall when I am \x02young\x03", "I am tall when I am <mark>young</mark>"},
+		{"<img src=x onerror=alert(1)> \x02candle\x03", "&lt;img src=x onerror=alert(1)&gt; <mark>candle</mark>"},
+		{"\x02Tom\x03 & \x02Jerry\x03's \"chase\"", "<mark>Tom</mark> &amp; <mark>Jerry</mark>&#39;s &#34;chase&#34;"},
+		{"</mark><script>", "&lt;/mark&gt;&lt;script&gt;"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.headline); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		text             string
+		excludeSolutions bool
+		want             string
+	}{
+		{"candle wa", false, "candle:* & wa:*"},
+		{"Candle, WAX!", true, "candle:*A & wax:*A"},
+		{"a' | !b", false, "a:* & b:*"},
+		{"&|!", false, ""},
+	}
+
+	for _, tt := range tests {
+		if got := SearchQuery(tt.text, tt.excludeSolutions); got != tt.want {
+			t.Errorf("SearchQuery(%q, %v) = %q, want %q", tt.text, tt.excludeSolutions, got, tt.want)
+		}
+	}
+}
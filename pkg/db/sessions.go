@@ -0,0 +1,80 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrSessionExpired   = errors.New("session expired")
+	ErrSessionExhausted = errors.New("session exhausted")
+)
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateSession shuffles every published riddle into a new play session
+func CreateSession(ttl time.Duration) (models.PlaySession, error) {
+	var session models.PlaySession
+
+	id, err := newSessionID()
+	if err != nil {
+		return session, err
+	}
+
+	// expired sessions are cleaned up lazily whenever a new one is created
+	if _, err := db.Exec("DELETE FROM play_sessions WHERE expires_at <= NOW()"); err != nil {
+		return session, err
+	}
+
+	query := `INSERT INTO play_sessions (id, riddle_ids, expires_at)
+		VALUES ($1, ARRAY(SELECT id FROM riddles WHERE published = TRUE AND deleted_at IS NULL ORDER BY RANDOM()), NOW() + $2 * INTERVAL '1 second')
+		RETURNING id, position, COALESCE(cardinality(riddle_ids), 0), expires_at`
+	err = db.QueryRow(query, id, int(ttl.Seconds())).Scan(&session.ID, &session.Position, &session.Total, &session.ExpiresAt)
+	return session, err
+}
+
+// AdvanceSession moves the session one step forward and returns the riddle id at the new position
+// every successful call also extends the expiry, so active sessions are never cut short
+func AdvanceSession(id string, ttl time.Duration) (int, models.PlaySession, error) {
+	var riddleID int
+	var session models.PlaySession
+
+	query := `UPDATE play_sessions
+		SET position = position + 1, expires_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id = $1 AND expires_at > NOW() AND position < COALESCE(cardinality(riddle_ids), 0)
+		RETURNING riddle_ids[position], id, position, cardinality(riddle_ids), expires_at`
+	err := db.QueryRow(query, id, int(ttl.Seconds())).Scan(&riddleID, &session.ID, &session.Position, &session.Total, &session.ExpiresAt)
+	if err == nil {
+		return riddleID, session, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, session, err
+	}
+
+	// nothing was updated, find out why
+	var expired, exhausted bool
+	query = "SELECT expires_at <= NOW(), position >= COALESCE(cardinality(riddle_ids), 0) FROM play_sessions WHERE id = $1"
+	err = db.QueryRow(query, id).Scan(&expired, &exhausted)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, session, ErrSessionNotFound
+	case err != nil:
+		return 0, session, err
+	case expired:
+		return 0, session, ErrSessionExpired
+	default:
+		return 0, session, ErrSessionExhausted
+	}
+}
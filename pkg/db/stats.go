@@ -0,0 +1,14 @@
+package db
+
+// RecordGuess counts a guess towards the riddle's popularity
+func RecordGuess(riddleID int, solved bool) error {
+	solves := 0
+	if solved {
+		solves = 1
+	}
+
+	query := `INSERT INTO riddle_stats (riddle_id, guesses, solves) VALUES ($1, 1, $2)
+		ON CONFLICT (riddle_id) DO UPDATE SET guesses = riddle_stats.guesses + 1, solves = riddle_stats.solves + EXCLUDED.solves`
+	_, err := db.Exec(query, riddleID, solves)
+	return err
+}
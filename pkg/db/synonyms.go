@@ -0,0 +1,82 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// NormalizeSynonyms trims synonyms and drops empty and case-insensitive duplicate entries
+func NormalizeSynonyms(synonyms []string) []string {
+	if synonyms == nil {
+		return nil
+	}
+
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, synonym := range synonyms {
+		synonym = strings.TrimSpace(synonym)
+		key := strings.ToLower(synonym)
+		if synonym == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, synonym)
+	}
+	return normalized
+}
+
+// AddSynonym appends a synonym unless the riddle already has it, ignoring case
+// it reports whether the synonym was added and returns the resulting list
+func AddSynonym(riddleID int, synonym string, editor string) (bool, []string, error) {
+	query := `UPDATE riddles SET synonyms = array_append(COALESCE(synonyms, '{}'), $2)
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM unnest(COALESCE(synonyms, '{}')) s WHERE lower(s) = lower($2))`
+	return changeSynonyms(riddleID, query, synonym, editor)
+}
+
+// RemoveSynonym removes a synonym, ignoring case, clearing the list once it is empty
+// it reports whether the synonym was removed and returns the resulting list
+func RemoveSynonym(riddleID int, synonym string, editor string) (bool, []string, error) {
+	query := `UPDATE riddles SET synonyms = NULLIF(ARRAY(SELECT s FROM unnest(synonyms) s WHERE lower(s) <> lower($2)), '{}')
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM unnest(COALESCE(synonyms, '{}')) s WHERE lower(s) = lower($2))`
+	return changeSynonyms(riddleID, query, synonym, editor)
+}
+
+func changeSynonyms(riddleID int, query string, synonym string, editor string) (bool, []string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockRiddle(tx, riddleID); err != nil {
+		return false, nil, err
+	}
+
+	if err := recordBaseline(tx, riddleID); err != nil {
+		return false, nil, err
+	}
+
+	result, err := tx.Exec(query, riddleID, synonym)
+	if err != nil {
+		return false, nil, err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, nil, err
+	}
+
+	if changed > 0 {
+		if err := recordRevision(tx, riddleID, "update", editor); err != nil {
+			return false, nil, err
+		}
+	}
+
+	var synonyms []string
+	if err := tx.QueryRow("SELECT synonyms FROM riddles WHERE id = $1", riddleID).Scan(pq.Array(&synonyms)); err != nil {
+		return false, nil, err
+	}
+
+	return changed > 0, synonyms, tx.Commit()
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/ionutinit/riddles-api/models"
+)
+
+var tagName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// NormalizeTags lowercases, trims and deduplicates tags, rejecting anything but letters, digits and dashes
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagName.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q, tags may only contain letters, digits and dashes", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// setRiddleTags replaces the tags of a riddle, creating tags that do not exist yet
+func setRiddleTags(tx *sql.Tx, riddleID int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM riddle_tags WHERE riddle_id = $1", riddleID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		var tagID int
+		query := "INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id"
+		if err := tx.QueryRow(query, tag).Scan(&tagID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("INSERT INTO riddle_tags (riddle_id, tag_id) VALUES ($1, $2)", riddleID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagsForRiddles loads the tags of several riddles in one query, keyed by riddle id
+func TagsForRiddles(ids []int) (map[int][]string, error) {
+	tags := make(map[int][]string, len(ids))
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	query := `SELECT rt.riddle_id, t.name FROM riddle_tags rt
+		JOIN tags t ON t.id = rt.tag_id
+		WHERE rt.riddle_id = ANY($1)
+		ORDER BY t.name`
+	rows, err := db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var riddleID int
+		var name string
+		if err := rows.Scan(&riddleID, &name); err != nil {
+			return nil, err
+		}
+		tags[riddleID] = append(tags[riddleID], name)
+	}
+	return tags, rows.Err()
+}
+
+// ListTags returns every tag used by a published riddle with the number of such riddles
+func ListTags() ([]models.TagCount, error) {
+	query := `SELECT t.name, COUNT(*) FROM tags t
+		JOIN riddle_tags rt ON rt.tag_id = t.id
+		JOIN riddles r ON r.id = rt.riddle_id
+		WHERE r.published = TRUE AND r.deleted_at IS NULL
+		GROUP BY t.name
+		ORDER BY t.name`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.TagCount
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"github.com/ionutinit/riddles-api/models"
+)
+
+// ListDeletedRiddles returns the trash, most recently deleted first
+func ListDeletedRiddles() ([]models.AdminRiddle, error) {
+	return listAdminRiddles("WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+}
+
+// RestoreRiddle takes a riddle out of the trash, it keeps the published state it had before deletion
+func RestoreRiddle(id int) (int64, error) {
+	result, err := db.Exec("UPDATE riddles SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeDeletedRiddles hard-deletes riddles that spent longer than the retention period in the trash, images included
+// the other tables referencing riddles cascade on delete
+func PurgeDeletedRiddles(retentionDays int) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expired := "SELECT id FROM riddles WHERE deleted_at < NOW() - $1 * INTERVAL '1 day'"
+
+	// images reference riddles without a foreign key, so they are removed explicitly
+	if _, err := tx.Exec("DELETE FROM images WHERE riddleId IN ("+expired+")", retentionDays); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec("DELETE FROM riddles WHERE id IN ("+expired+")", retentionDays)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
@@ -6,9 +6,12 @@ import (
 	"path/filepath"
 
 	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/openapi"
+	"github.com/ionutinit/riddles-api/pkg/problem"
 	"github.com/sirupsen/logrus"
 )
 
+// ApiPageHandler serves the API page, whose explorer loads the OpenAPI document from SpecURL
 func ApiPageHandler(w http.ResponseWriter, r *http.Request) {
 	tmplPath := filepath.Join("templates", "index.html")
 	tmpl, err := template.ParseFiles(tmplPath)
@@ -17,16 +20,28 @@ func ApiPageHandler(w http.ResponseWriter, r *http.Request) {
 			"error": err,
 			"handler": "ApiPageHandler",
 		}).Error("Error parsing HTML template")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
 		return
 	}
 
-	err = tmpl.Execute(w, nil)
+	data := struct {
+		SpecURL string
+	}{
+		SpecURL: constructURL(r, "/api/openapi.json"),
+	}
+
+	err = tmpl.Execute(w, data)
 	if err != nil {
 		logger.Log.WithFields(logrus.Fields{
 			"error": err,
 			"handler": "ApiPageHandler",
 		}).Error("Error executing HTML template")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
 	}
-}
\ No newline at end of file
+}
+
+// OpenAPIHandler serves the OpenAPI document, which the explorer on the API page reads
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec)
+}
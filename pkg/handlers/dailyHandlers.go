@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/middleware"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/router"
+)
+
+const dateLayout = "2006-01-02"
+
+type DailyRiddleResponse struct {
+	Date string `json:"date"`
+	// true when an admin scheduled the riddle for this date
+	Scheduled bool `json:"scheduled"`
+	// the riddle as shaped by the presenter of the API version
+	Riddle interface{}   `json:"riddle"`
+	Links  []models.Link `json:"links,omitempty"`
+}
+
+type DailyScheduleRequest struct {
+	RiddleID int `json:"riddle_id"`
+}
+
+func dailyRepeatWindow() int {
+	if days := config.AppConfig.Daily.RepeatWindowDays; days > 0 {
+		return days
+	}
+	return 30
+}
+
+// dailyDate resolves ?date= or, without it, today, along with today in ?tz= or the configured time zone
+func dailyDate(r *http.Request) (time.Time, time.Time, error) {
+	today, err := dailyToday(r)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if date := r.URL.Query().Get("date"); date != "" {
+		day, err := time.Parse(dateLayout, date)
+		return day, today, err
+	}
+	return today, today, nil
+}
+
+func dailyToday(r *http.Request) (time.Time, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		tz = config.AppConfig.Daily.Timezone
+	}
+
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now().In(location)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+func GetDailyRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetDailyRiddleHandler")
+
+	day, today, err := dailyDate(r)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"query":   r.URL.RawQuery,
+			"error":   err,
+			"handler": "GetDailyRiddleHandler",
+		}).Warn("Invalid date or time zone")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid date or time zone, expected ?date=YYYY-MM-DD and an IANA ?tz=")
+		return
+	}
+
+	// only admins may see what is coming, everybody else would spoil the riddles of the next days
+	if day.After(today) && !middleware.IsRequestAllowed(r, config.AppConfig.AllowedIPs) {
+		logger.Log.WithFields(logrus.Fields{
+			"date":    day.Format(dateLayout),
+			"handler": "GetDailyRiddleHandler",
+		}).Warn("Access to a future daily riddle denied due to IP restrictions")
+		problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, "The riddle of a future date is not public yet")
+		return
+	}
+
+	if day.After(today) {
+		// a preview for admins must not end up in a shared cache
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+
+	rdlBase, scheduled, err := db.GetDailyRiddle(day, day.Equal(today), dailyRepeatWindow())
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"date":    day.Format(dateLayout),
+			"error":   err,
+			"handler": "GetDailyRiddleHandler",
+		}).Error("Error picking daily riddle")
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "No published riddles")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	// the riddle of a day can change with a new schedule, so only the ETag validates it, not the riddle's date
+	if notModified(w, r, fmt.Sprintf(`"%s-%d-%d%s"`, day.Format(dateLayout), rdlBase.ID, rdlBase.Version, etagSuffix(r)), time.Time{}) {
+		return
+	}
+
+	hideSolution(r, &rdlBase)
+	response := DailyRiddleResponse{
+		Date:      day.Format(dateLayout),
+		Scheduled: scheduled,
+		Riddle: presenterFor(r).riddle(models.RiddleResponse{
+			RiddleBase: rdlBase,
+			Links: append([]models.Link{
+				{Rel: "self", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
+			}, playLinks(r, rdlBase.ID)...),
+		}),
+		Links: []models.Link{
+			{Rel: "previous", Href: constructURL(r, "/api/riddles/daily?date="+day.AddDate(0, 0, -1).Format(dateLayout))},
+		},
+	}
+	if next := day.AddDate(0, 0, 1); !next.After(today) || middleware.IsRequestAllowed(r, config.AppConfig.AllowedIPs) {
+		response.Links = append(response.Links, models.Link{Rel: "next", Href: constructURL(r, "/api/riddles/daily?date="+next.Format(dateLayout))})
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"date":     response.Date,
+		"riddleID": rdlBase.ID,
+		"handler":  "GetDailyRiddleHandler",
+	}).Info("Successfully executed GetDailyRiddleHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// dailyDateFromPath extracts {date} from /api/riddles/daily/{date}
+func dailyDateFromPath(w http.ResponseWriter, r *http.Request, handler string) (time.Time, bool) {
+	day, err := time.Parse(dateLayout, router.Param(r, "date"))
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"path":    r.URL.Path,
+			"error":   err,
+			"handler": handler,
+		}).Error("Invalid date in path")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid date, expected YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+func ScheduleDailyRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing ScheduleDailyRiddleHandler")
+
+	day, ok := dailyDateFromPath(w, r, "ScheduleDailyRiddleHandler")
+	if !ok {
+		return
+	}
+
+	var request DailyScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "ScheduleDailyRiddleHandler",
+		}).Error("Error decoding request body")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+		return
+	}
+
+	if request.RiddleID == 0 {
+		problem.Write(w, r, problem.Validation("Missing required field: riddle_id", problem.FieldError{Field: "riddle_id", Message: "is required"}))
+		return
+	}
+
+	if !riddleExistsOrError(w, r, request.RiddleID, "ScheduleDailyRiddleHandler") {
+		return
+	}
+
+	if err := db.ScheduleDailyRiddle(day, request.RiddleID); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"date":     day.Format(dateLayout),
+			"riddleID": request.RiddleID,
+			"error":    err,
+			"handler":  "ScheduleDailyRiddleHandler",
+		}).Error("Error scheduling daily riddle")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error scheduling daily riddle")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Daily riddle scheduled successfully",
+		"links": []models.Link{
+			{Rel: "view", Href: constructURL(r, "/api/riddles/daily?date="+day.Format(dateLayout))},
+			{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", request.RiddleID))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"date":     day.Format(dateLayout),
+		"riddleID": request.RiddleID,
+		"handler":  "ScheduleDailyRiddleHandler",
+	}).Info("Successfully executed ScheduleDailyRiddleHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func UnscheduleDailyRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing UnscheduleDailyRiddleHandler")
+
+	day, ok := dailyDateFromPath(w, r, "UnscheduleDailyRiddleHandler")
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := db.UnscheduleDailyRiddle(day)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"date":    day.Format(dateLayout),
+			"error":   err,
+			"handler": "UnscheduleDailyRiddleHandler",
+		}).Error("Error removing daily riddle schedule")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error removing schedule")
+		return
+	}
+
+	if rowsAffected == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "No riddle scheduled for this date")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Daily riddle schedule removed successfully",
+		"links": []models.Link{
+			{Rel: "view", Href: constructURL(r, "/api/riddles/daily?date="+day.Format(dateLayout))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"date":    day.Format(dateLayout),
+		"handler": "UnscheduleDailyRiddleHandler",
+	}).Info("Successfully executed UnscheduleDailyRiddleHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -4,19 +4,19 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
-	"strconv"
-	"strings"
 
+	"github.com/lib/pq"
 	openai "github.com/sashabaranov/go-openai"
 	"github.com/sirupsen/logrus"
 
 	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/config"
 	"github.com/ionutinit/riddles-api/pkg/db"
 	"github.com/ionutinit/riddles-api/pkg/logger"
-	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/problem"
 )
 
 type Response struct {
@@ -31,39 +31,17 @@ type ImageStyle struct {
 func GenerateImageHandler(w http.ResponseWriter, r *http.Request) {
     logger.Log.Info("Executing GenerateImageHandler")
 
-    path := r.URL.Path
-    parts := strings.Split(path, "/")
-    if len(parts) != 5 {
-        logger.Log.WithFields(logrus.Fields{
-            "path": path,
-            "handler": "GenerateImageHandler",
-        }).Error("Invalid request path")
-        http.Error(w, "Invalid request", http.StatusBadRequest)
-        return
-    }
-
-    id, err := strconv.Atoi(parts[4])
-    if err != nil {
-        logger.Log.WithFields(logrus.Fields{
-            "extractedId": id,
-            "error": err,
-            "handler": "GenerateImageHandler",
-        })
-        http.Error(w, "Invalid ID", http.StatusBadRequest)
+    id, ok := riddleIDFromPath(w, r, "GenerateImageHandler")
+    if !ok {
         return
     }
 
     database := db.GetDB()
-    row := database.QueryRow("SELECT id, riddle, solution, synonyms FROM riddles WHERE id = $1", id)
+    row := database.QueryRow("SELECT id, riddle, solution, synonyms FROM riddles WHERE id = $1 AND deleted_at IS NULL", id)
 
     var rdl models.RiddleBase
-    if err := row.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, &rdl.Synonyms); err != nil{
-        logger.Log.WithFields(logrus.Fields{
-            "id": id,
-            "error": err,
-            "handler": "GenerateImageHandler",
-        }).Error("Error scanning riddles table rows")
-        http.Error(w, "Riddle not found", http.StatusNotFound)
+    if err := row.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, pq.Array(&rdl.Synonyms)); err != nil{
+        writeRiddleError(w, r, id, err, "GenerateImageHandler")
         return
     }
 
@@ -77,7 +55,7 @@ func GenerateImageHandler(w http.ResponseWriter, r *http.Request) {
                 "error": err,
                 "handler": "GenerateImageHandler",
             }).Error("Error parsing request body")
-            http.Error(w, "Error parsing request body", http.StatusBadRequest)
+            problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
             return
         }
     }
@@ -104,7 +82,7 @@ func GenerateImageHandler(w http.ResponseWriter, r *http.Request) {
             "error": err,
             "handler": "GenerateImageHandler",
         }).Error("Error generating image")
-        http.Error(w, fmt.Sprintf("Error generating image: %v", err), http.StatusInternalServerError)
+        problem.Error(w, r, http.StatusBadGateway, problem.CodeUpstream, "Error generating image")
         return
     }
     imageUrl := respUrl.Data[0].URL
@@ -120,6 +98,7 @@ func GenerateImageHandler(w http.ResponseWriter, r *http.Request) {
         "handler": "GenerateImage Handler",
     }).Info("Succesfully sent riddle and image URL to client")
 
+    apiversion.Unversioned(r)
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(response)
 
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+func duplicateThreshold() float64 {
+	if similarity := config.AppConfig.Duplicates.Similarity; similarity > 0 && similarity <= 1 {
+		return similarity
+	}
+	return 0.6
+}
+
+// duplicateWarnings lists the near duplicates of a new riddle, failing to find them never fails the submission
+func duplicateWarnings(r *http.Request, riddle models.Riddle, id int) []models.DuplicateWarning {
+	warnings, err := db.SimilarRiddles(riddle.Riddle, riddle.Solution, id, duplicateThreshold())
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":    id,
+			"error": err,
+		}).Error("Error looking for near duplicates")
+		return nil
+	}
+
+	for i := range warnings {
+		warnings[i].Message = fmt.Sprintf("Similar to riddle %d with the same solution", warnings[i].ID)
+		if warnings[i].Published {
+			warnings[i].Links = []models.Link{
+				{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", warnings[i].ID))},
+			}
+		}
+	}
+	return warnings
+}
+
+func GetDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetDuplicatesHandler")
+
+	clusters, err := db.DuplicateClusters(duplicateThreshold())
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GetDuplicatesHandler",
+		}).Error("Error querying duplicate riddles")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	response := []models.DuplicateCluster{}
+	for _, cluster := range clusters {
+		for i := range cluster.Riddles {
+			id := cluster.Riddles[i].ID
+			cluster.Riddles[i].Links = []models.Link{
+				{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+				{Rel: "delete", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+			}
+		}
+		response = append(response, cluster)
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"clusters": len(response),
+		"handler":  "GetDuplicatesHandler",
+	}).Info("Successfully executed GetDuplicatesHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenterFor(r).duplicates(response))
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/middleware"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+// the response is flushed to the client every exportFlushRows rows
+const exportFlushRows = 100
+
+var exportContentTypes = map[string]string{
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"csv":    "text/csv; charset=utf-8",
+}
+
+// ExportRiddlesHandler streams every riddle matching the listing filters as JSON, NDJSON or CSV.
+// Solutions follow ?include=solution like the listing. Admins can also ask for
+// ?include=unpublished to add riddles that are not public and ?include=metadata for the full admin view,
+// which always carries the solution.
+func ExportRiddlesHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing ExportRiddlesHandler")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		logger.Log.WithFields(logrus.Fields{
+			"format":  format,
+			"handler": "ExportRiddlesHandler",
+		}).Warn("Invalid export format")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid format: use json, ndjson or csv")
+		return
+	}
+
+	filter, err := parseRiddleFilter(r)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "ExportRiddlesHandler",
+		}).Warn("Invalid export filters")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	metadata, unpublished := includes(r, "metadata"), includes(r, "unpublished")
+	if (metadata || unpublished) && !middleware.IsRequestAllowed(r, config.AppConfig.AllowedIPs) {
+		logger.Log.WithFields(logrus.Fields{
+			"remoteAddr": r.RemoteAddr,
+			"handler":    "ExportRiddlesHandler",
+		}).Warn("Admin export denied due to IP restrictions")
+		problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, "Access denied")
+		return
+	}
+	filter.IncludeUnpublished = unpublished
+
+	writer := newExportWriter(format, w, presenterFor(r), metadata, metadata || includesSolution(r))
+	flusher, _ := w.(http.Flusher)
+
+	// headers are only sent with the first row, so a failing query can still be answered with a 500
+	count := 0
+	start := func() {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="riddles.`+format+`"`)
+	}
+
+	err = db.ExportRiddles(filter, func(rdl models.AdminRiddle) error {
+		if count == 0 {
+			start()
+		}
+		count++
+		if err := writer.write(rdl); err != nil {
+			return err
+		}
+		if flusher != nil && count%exportFlushRows == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"format":  format,
+			"rows":    count,
+			"error":   err,
+			"handler": "ExportRiddlesHandler",
+		}).Error("Error exporting riddles")
+		// once rows went out the status is already sent, the truncated body tells the client something went wrong
+		if count == 0 {
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		}
+		return
+	}
+
+	if count == 0 {
+		start()
+	}
+	if err := writer.close(); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "ExportRiddlesHandler",
+		}).Error("Error finishing export")
+		return
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"format":  format,
+		"rows":    count,
+		"handler": "ExportRiddlesHandler",
+	}).Info("Successfully executed ExportRiddlesHandler")
+}
+
+type exportWriter interface {
+	write(rdl models.AdminRiddle) error
+	close() error
+}
+
+func newExportWriter(format string, w io.Writer, p presenter, metadata bool, withSolution bool) exportWriter {
+	switch format {
+	case "ndjson":
+		return &ndjsonExport{encoder: json.NewEncoder(w), presenter: p, metadata: metadata, withSolution: withSolution}
+	case "csv":
+		return &csvExport{writer: csv.NewWriter(w), metadata: metadata, withSolution: withSolution}
+	default:
+		return &jsonExport{w: w, encoder: json.NewEncoder(w), presenter: p, metadata: metadata, withSolution: withSolution}
+	}
+}
+
+// exportRecord is the admin view when metadata was asked for, otherwise the public one, in the shape of the API version
+func exportRecord(p presenter, rdl models.AdminRiddle, metadata bool, withSolution bool) interface{} {
+	rdl.Links = nil
+	if metadata {
+		return p.adminRiddle(rdl)
+	}
+
+	base := rdl.RiddleBase
+	if !withSolution {
+		base.Solution = ""
+		base.Synonyms = nil
+	}
+	return p.riddle(models.RiddleResponse{RiddleBase: base, Tags: rdl.Tags})
+}
+
+// jsonExport writes a single array, one element at a time
+type jsonExport struct {
+	w            io.Writer
+	encoder      *json.Encoder
+	presenter    presenter
+	metadata     bool
+	withSolution bool
+	started      bool
+}
+
+func (e *jsonExport) write(rdl models.AdminRiddle) error {
+	separator := ","
+	if !e.started {
+		separator = "["
+		e.started = true
+	}
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	return e.encoder.Encode(exportRecord(e.presenter, rdl, e.metadata, e.withSolution))
+}
+
+func (e *jsonExport) close() error {
+	closing := "]\n"
+	if !e.started {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+type ndjsonExport struct {
+	encoder      *json.Encoder
+	presenter    presenter
+	metadata     bool
+	withSolution bool
+}
+
+func (e *ndjsonExport) write(rdl models.AdminRiddle) error {
+	return e.encoder.Encode(exportRecord(e.presenter, rdl, e.metadata, e.withSolution))
+}
+
+func (e *ndjsonExport) close() error {
+	return nil
+}
+
+// csvExport uses the import column names, so an export can be imported again.
+// Synonyms and tags are joined with "|".
+type csvExport struct {
+	writer       *csv.Writer
+	metadata     bool
+	withSolution bool
+	started      bool
+}
+
+func (e *csvExport) header() []string {
+	header := []string{"id", "riddle"}
+	if e.withSolution {
+		header = append(header, "solution", "synonyms")
+	}
+	header = append(header, "tags")
+	if e.metadata {
+		header = append(header, "username", "user_email", "published", "date_created", "reviewed_by", "reviewed_at", "rejection_reason")
+	}
+	return header
+}
+
+func (e *csvExport) writeHeader() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return e.writer.Write(e.header())
+}
+
+func (e *csvExport) write(rdl models.AdminRiddle) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	record := []string{strconv.Itoa(rdl.ID), rdl.Riddle}
+	if e.withSolution {
+		record = append(record, rdl.Solution, strings.Join(rdl.Synonyms, "|"))
+	}
+	record = append(record, strings.Join(rdl.Tags, "|"))
+	if e.metadata {
+		record = append(record,
+			stringOrEmpty(rdl.Username),
+			stringOrEmpty(rdl.UserEmail),
+			strconv.FormatBool(rdl.Published),
+			rdl.DateCreated.Format(time.RFC3339),
+			stringOrEmpty(rdl.ReviewedBy),
+			timeOrEmpty(rdl.ReviewedAt),
+			stringOrEmpty(rdl.RejectionReason),
+		)
+	}
+
+	if err := e.writer.Write(record); err != nil {
+		return err
+	}
+	// csv.Writer buffers, flushing every row keeps the export streaming
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExport) close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func timeOrEmpty(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/answers"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+// maxGuessBytes leaves room for an answer of answers.MaxAnswerLength characters, escaped
+const maxGuessBytes = 4 << 10
+
+type GuessRequest struct {
+	Answer string `json:"answer"`
+}
+
+type GuessResponse struct {
+	ID      int           `json:"id"`
+	Correct bool          `json:"correct"`
+	Result  string        `json:"result"`
+	Links   []models.Link `json:"links,omitempty"`
+}
+
+func GuessRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GuessRiddleHandler")
+
+	id, ok := riddleIDFromPath(w, r, "GuessRiddleHandler")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGuessBytes)
+
+	var guess GuessRequest
+	if err := json.NewDecoder(r.Body).Decode(&guess); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GuessRiddleHandler",
+		}).Error("Error decoding request body")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+		return
+	}
+
+	if guess.Answer == "" {
+		logger.Log.WithFields(logrus.Fields{
+			"handler": "GuessRiddleHandler",
+		}).Warn("Missing required fields in request")
+		problem.Write(w, r, problem.Validation("Missing required field: answer", problem.FieldError{Field: "answer", Message: "is required"}))
+		return
+	}
+	if len([]rune(guess.Answer)) > answers.MaxAnswerLength {
+		logger.Log.WithFields(logrus.Fields{
+			"handler": "GuessRiddleHandler",
+		}).Warn("Answer too long")
+		problem.Write(w, r, problem.Validation("Answer too long",
+			problem.FieldError{Field: "answer", Message: fmt.Sprintf("must be at most %d characters", answers.MaxAnswerLength)}))
+		return
+	}
+
+	rdlBase, err := db.GetPublishedRiddle(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "GuessRiddleHandler",
+		}).Error("Error scanning riddles table rows")
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	result := answers.Check(guess.Answer, rdlBase.Solution, rdlBase.Synonyms, matchingTolerance())
+
+	if err := db.RecordGuess(rdlBase.ID, result == answers.Correct); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "GuessRiddleHandler",
+		}).Error("Error recording guess statistics")
+	}
+
+	response := GuessResponse{
+		ID:      rdlBase.ID,
+		Correct: result == answers.Correct,
+		Result:  result,
+		Links: []models.Link{
+			{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
+			{Rel: "random", Href: constructURL(r, "/api/riddles/random")},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"result":  response.Result,
+		"handler": "GuessRiddleHandler",
+	}).Info("Successfully executed GuessRiddleHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// matchingTolerance reads the per-deployment strictness, fields missing from the config fall back to the package defaults
+func matchingTolerance() answers.Tolerance {
+	matching := config.AppConfig.Matching
+	tolerance := answers.DefaultTolerance
+	if matching.CorrectRatio != nil {
+		tolerance.CorrectRatio = *matching.CorrectRatio
+	}
+	if matching.CloseRatio != nil {
+		tolerance.CloseRatio = *matching.CloseRatio
+	}
+	if matching.MinLength != nil {
+		tolerance.MinLength = *matching.MinLength
+	}
+	return tolerance
+}
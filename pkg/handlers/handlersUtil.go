@@ -1,9 +1,22 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
 	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/router"
 )
 
 func constructURL(req *http.Request, path string) string {
@@ -11,5 +24,112 @@ func constructURL(req *http.Request, path string) string {
 	if baseURL == "" {
 		baseURL = "http://" + req.Host
 	}
+	// links stay under /api/v1 or /api/v2 when the request came through one of them
+	// paths copied from the request URL already are
+	if prefix := apiversion.PathPrefix(req); prefix != "" && strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, prefix+"/") {
+		path = prefix + strings.TrimPrefix(path, "/api")
+	}
 	return baseURL + path
 }
+
+// riddleIDFromPath reads the {id} parameter of the matched route, for /api/riddles/{id}/... and /api/admin/riddles/{id}/...
+// it writes the error response itself, so callers only need to return when ok is false
+func riddleIDFromPath(w http.ResponseWriter, r *http.Request, handler string) (int, bool) {
+	return intParamFromPath(w, r, handler, "id")
+}
+
+func intParamFromPath(w http.ResponseWriter, r *http.Request, handler string, name string) (int, bool) {
+	value, err := router.IntParam(r, name)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"path":    r.URL.Path,
+			"param":   name,
+			"error":   err,
+			"handler": handler,
+		}).Error("Invalid number in path")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidID, "Invalid ID")
+		return 0, false
+	}
+
+	return value, true
+}
+
+// writeRiddleError answers a failed riddle lookup, a missing riddle is a 404 and anything else a 500
+func writeRiddleError(w http.ResponseWriter, r *http.Request, id int, err error, handler string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": handler,
+		}).Warn("ID not matching any riddle")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+		return
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"error":   err,
+		"handler": handler,
+	}).Error("Error scanning riddles table rows")
+	problem.Write(w, r, problem.FromError(err))
+}
+
+// editorFromRequest identifies who made an admin change, from the X-Editor header or else the client IP
+func editorFromRequest(r *http.Request) string {
+	if editor := strings.TrimSpace(r.Header.Get("X-Editor")); editor != "" {
+		return editor
+	}
+
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return clientIP
+}
+
+// includesSolution reports whether the client asked for spoilers with ?include=solution
+func includesSolution(r *http.Request) bool {
+	return includes(r, "solution")
+}
+
+// includes reports whether name is one of the comma separated values of ?include=
+func includes(r *http.Request, name string) bool {
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(include) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hideSolution strips the solution and synonyms unless they were explicitly requested
+func hideSolution(r *http.Request, rdl *models.RiddleBase) {
+	if includesSolution(r) {
+		return
+	}
+	rdl.Solution = ""
+	rdl.Synonyms = nil
+}
+
+// playLinks point to the actions that let a client play a riddle without spoiling it
+func playLinks(r *http.Request, id int) []models.Link {
+	return []models.Link{
+		{Rel: "hint", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/hints/1", id))},
+		{Rel: "guess", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/guess", id))},
+		{Rel: "solution", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/solution", id))},
+	}
+}
+
+// riddleTags loads the tags of the given riddles, writing a 500 when they cannot be loaded
+func riddleTags(w http.ResponseWriter, r *http.Request, handler string, ids ...int) (map[int][]string, bool) {
+	tags, err := db.TagsForRiddles(ids)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"ids":     ids,
+			"error":   err,
+			"handler": handler,
+		}).Error("Error querying riddle tags")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return nil, false
+	}
+	return tags, true
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/answers"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/router"
+)
+
+type HintRequest struct {
+	Hint     string `json:"hint"`
+	Position int    `json:"position,omitempty"`
+}
+
+type HintOrderRequest struct {
+	Order []int `json:"order"`
+}
+
+// hintNumberFromPath extracts {n} from /api/riddles/{id}/hints/{n}
+func hintNumberFromPath(w http.ResponseWriter, r *http.Request, handler string) (int, bool) {
+	n, err := router.IntParam(r, "n")
+	if err != nil || n < 1 {
+		logger.Log.WithFields(logrus.Fields{
+			"path":    r.URL.Path,
+			"handler": handler,
+		}).Error("Invalid hint number in path")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid hint number")
+		return 0, false
+	}
+	return n, true
+}
+
+func GetHintHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetHintHandler")
+
+	id, ok := riddleIDFromPath(w, r, "GetHintHandler")
+	if !ok {
+		return
+	}
+
+	n, ok := hintNumberFromPath(w, r, "GetHintHandler")
+	if !ok {
+		return
+	}
+
+	rdlBase, err := db.GetPublishedRiddle(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "GetHintHandler",
+		}).Error("Error scanning riddles table rows")
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	curated, err := db.GetHints(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "GetHintHandler",
+		}).Error("Error querying hints")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	var hints []string
+	for _, hint := range curated {
+		hints = append(hints, hint.Hint)
+	}
+
+	derived := len(hints) == 0
+	if derived {
+		hints = answers.DerivedHints(rdlBase.Solution)
+	}
+
+	if n > len(hints) {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"number":  n,
+			"handler": "GetHintHandler",
+		}).Warn("Hint number out of range")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Hint not found")
+		return
+	}
+
+	links := []models.Link{
+		{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+	}
+	if n < len(hints) {
+		links = append(links, models.Link{Rel: "next", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/hints/%d", id, n+1))})
+	}
+	links = append(links, playLinks(r, id)...)
+
+	response := models.HintResponse{
+		RiddleID: id,
+		Number:   n,
+		Total:    len(hints),
+		Hint:     hints[n-1],
+		Derived:  derived,
+		Links:    links,
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"number":  n,
+		"handler": "GetHintHandler",
+	}).Info("Successfully executed GetHintHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// riddleExistsOrError writes a 404 or 500 when the riddle cannot be used by the hint admin endpoints
+func riddleExistsOrError(w http.ResponseWriter, r *http.Request, id int, handler string) bool {
+	exists, err := db.RiddleExists(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": handler,
+		}).Error("Error checking riddle existence")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return false
+	}
+
+	if !exists {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": handler,
+		}).Warn("ID not matching any riddle")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+		return false
+	}
+	return true
+}
+
+func PostHintHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing PostHintHandler")
+
+	id, ok := riddleIDFromPath(w, r, "PostHintHandler")
+	if !ok {
+		return
+	}
+
+	var request HintRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "PostHintHandler",
+		}).Error("Error decoding request body")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+		return
+	}
+
+	if strings.TrimSpace(request.Hint) == "" {
+		logger.Log.WithFields(logrus.Fields{
+			"handler": "PostHintHandler",
+		}).Warn("Missing required fields in request")
+		problem.Write(w, r, problem.Validation("Missing required field: hint", problem.FieldError{Field: "hint", Message: "is required"}))
+		return
+	}
+
+	if !riddleExistsOrError(w, r, id, "PostHintHandler") {
+		return
+	}
+
+	hint, err := db.InsertHint(id, request.Hint, request.Position)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "PostHintHandler",
+		}).Error("Error inserting new hint in the database")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error inserting new hint")
+		return
+	}
+
+	response := map[string]interface{}{
+		"hint": hint,
+		"links": []models.Link{
+			{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/hints/%d", id, hint.Position))},
+			{Rel: "delete", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/hints/%d", id, hint.Position))},
+			{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":       id,
+		"position": hint.Position,
+		"handler":  "PostHintHandler",
+	}).Info("Successfully executed PostHintHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func ReorderHintsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing ReorderHintsHandler")
+
+	id, ok := riddleIDFromPath(w, r, "ReorderHintsHandler")
+	if !ok {
+		return
+	}
+
+	var request HintOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "ReorderHintsHandler",
+		}).Error("Error decoding request body")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+		return
+	}
+
+	if !riddleExistsOrError(w, r, id, "ReorderHintsHandler") {
+		return
+	}
+
+	if err := db.ReorderHints(id, request.Order); err != nil {
+		if errors.Is(err, db.ErrInvalidHintOrder) {
+			logger.Log.WithFields(logrus.Fields{
+				"id":      id,
+				"order":   request.Order,
+				"handler": "ReorderHintsHandler",
+			}).Warn("Invalid hint order in request")
+			problem.Write(w, r, problem.Validation(err.Error(), problem.FieldError{Field: "order", Message: "must list every hint position exactly once"}))
+			return
+		}
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "ReorderHintsHandler",
+		}).Error("Error reordering hints")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error reordering hints")
+		return
+	}
+
+	hints, err := db.GetHints(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "ReorderHintsHandler",
+		}).Error("Error querying hints")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Hints reordered successfully",
+		"hints":   hints,
+		"links": []models.Link{
+			{Rel: "first-hint", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/hints/1", id))},
+			{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"handler": "ReorderHintsHandler",
+	}).Info("Successfully executed ReorderHintsHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteHintHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing DeleteHintHandler")
+
+	id, ok := riddleIDFromPath(w, r, "DeleteHintHandler")
+	if !ok {
+		return
+	}
+
+	n, ok := hintNumberFromPath(w, r, "DeleteHintHandler")
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := db.DeleteHint(id, n)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"number":  n,
+			"error":   err,
+			"handler": "DeleteHintHandler",
+		}).Error("Error deleting hint from database")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error deleting hint")
+		return
+	}
+
+	if rowsAffected == 0 {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"number":  n,
+			"handler": "DeleteHintHandler",
+		}).Warn("Hint number not matching any hint for deletion")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Hint not found")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Hint deleted successfully",
+		"links": []models.Link{
+			{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"number":  n,
+		"handler": "DeleteHintHandler",
+	}).Info("Successfully executed DeleteHintHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
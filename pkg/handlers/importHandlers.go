@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 5000
+)
+
+// import modes, atomic stores every row or none, partial stores the valid rows and reports the rest
+const (
+	importAtomic  = "atomic"
+	importPartial = "partial"
+)
+
+// row statuses in the import report
+const (
+	importCreated = "created"
+	importValid   = "valid"
+	importInvalid = "invalid"
+	importFailed  = "failed"
+	importSkipped = "skipped"
+)
+
+// parsedRow keeps the riddle together with whatever made it unusable
+type parsedRow struct {
+	riddle models.Riddle
+	err    error
+}
+
+// ImportRiddlesHandler creates riddles in bulk from a JSON array, an NDJSON stream or a CSV file.
+// The CSV header names the columns riddle, solution, synonyms, tags, username and user_email,
+// synonyms and tags hold several values separated by "|".
+func ImportRiddlesHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing ImportRiddlesHandler")
+
+	query := r.URL.Query()
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = importAtomic
+	}
+	if mode != importAtomic && mode != importPartial {
+		logger.Log.WithFields(logrus.Fields{
+			"mode":    mode,
+			"handler": "ImportRiddlesHandler",
+		}).Warn("Invalid import mode")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid mode: use atomic or partial")
+		return
+	}
+
+	dryRun, err := parseBoolParam(query.Get("dry_run"))
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid dry_run: use true or false")
+		return
+	}
+
+	publish, err := parseBoolParam(query.Get("publish"))
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid publish: use true or false")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	rows, err := parseImport(r)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "ImportRiddlesHandler",
+		}).Warn("Error reading import")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, err.Error())
+		return
+	}
+
+	if len(rows) == 0 {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "Nothing to import")
+		return
+	}
+	if len(rows) > maxImportRows {
+		problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.CodeTooLarge, fmt.Sprintf("Too many rows: at most %d can be imported at once", maxImportRows))
+		return
+	}
+
+	report := models.ImportReport{Mode: mode, DryRun: dryRun, Total: len(rows)}
+	invalid := 0
+	for i := range rows {
+		result := models.ImportRow{Row: i + 1, Status: importValid}
+		if rows[i].err != nil {
+			result.Status = importInvalid
+			result.Errors = errorMessages(rows[i].err)
+			invalid++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	report.Failed = invalid
+
+	status := http.StatusOK
+	switch {
+	case dryRun:
+		// nothing is written, the report tells which rows would be accepted, duplicates included
+		duplicates, err := checkImportDuplicates(r, rows, &report)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":   err,
+				"handler": "ImportRiddlesHandler",
+			}).Error("Error checking imported riddles for duplicates")
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error importing riddles")
+			return
+		}
+		report.Failed += duplicates
+	case mode == importAtomic && invalid > 0:
+		skipRows(&report)
+		status = http.StatusUnprocessableEntity
+	case mode == importAtomic:
+		riddles := make([]models.Riddle, len(rows))
+		for i := range rows {
+			riddles[i] = rows[i].riddle
+		}
+
+		ids, err := db.InsertRiddles(riddles, editorFromRequest(r), publish)
+		var importErr *db.ImportError
+		if errors.As(err, &importErr) {
+			logger.Log.WithFields(logrus.Fields{
+				"row":     importErr.Row + 1,
+				"error":   importErr.Err,
+				"handler": "ImportRiddlesHandler",
+			}).Error("Error inserting imported riddle, import rolled back")
+			markFailed(r, &report.Rows[importErr.Row], importErr.Err)
+			report.Failed = 1
+			skipRows(&report)
+			status = http.StatusUnprocessableEntity
+			break
+		}
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":   err,
+				"handler": "ImportRiddlesHandler",
+			}).Error("Error importing riddles")
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error importing riddles")
+			return
+		}
+
+		for i, id := range ids {
+			markCreated(r, &report.Rows[i], id)
+		}
+		report.Created = len(ids)
+		status = http.StatusCreated
+	default:
+		for i := range rows {
+			if rows[i].err != nil {
+				continue
+			}
+
+			ids, err := db.InsertRiddles([]models.Riddle{rows[i].riddle}, editorFromRequest(r), publish)
+			if err != nil {
+				logger.Log.WithFields(logrus.Fields{
+					"row":     i + 1,
+					"error":   err,
+					"handler": "ImportRiddlesHandler",
+				}).Error("Error inserting imported riddle")
+				markFailed(r, &report.Rows[i], err)
+				report.Failed++
+				continue
+			}
+
+			markCreated(r, &report.Rows[i], ids[0])
+			report.Created++
+		}
+
+		status = http.StatusCreated
+		if report.Created == 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"mode":    mode,
+		"dryRun":  dryRun,
+		"total":   report.Total,
+		"created": report.Created,
+		"failed":  report.Failed,
+		"handler": "ImportRiddlesHandler",
+	}).Info("Successfully executed ImportRiddlesHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// errorMessages lists the invalid fields of a validation problem, or the error itself
+func errorMessages(err error) []string {
+	var p *problem.Problem
+	if !errors.As(err, &p) || len(p.Errors) == 0 {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, 0, len(p.Errors))
+	for _, field := range p.Errors {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+	return messages
+}
+
+func markCreated(r *http.Request, row *models.ImportRow, id int) {
+	row.Status = importCreated
+	row.ID = &id
+	row.Links = []models.Link{
+		{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+	}
+}
+
+// checkImportDuplicates runs the duplicate check of the import on the valid rows without storing them, so a dry run
+// reports the rows the real import would refuse. It returns how many rows were found to be duplicates.
+func checkImportDuplicates(r *http.Request, rows []parsedRow, report *models.ImportReport) (int, error) {
+	var valid []int
+	var riddles []models.Riddle
+	for i := range rows {
+		if rows[i].err == nil {
+			valid = append(valid, i)
+			riddles = append(riddles, rows[i].riddle)
+		}
+	}
+	if len(riddles) == 0 {
+		return 0, nil
+	}
+
+	errs, err := db.CheckImport(riddles)
+	if err != nil {
+		return 0, err
+	}
+
+	duplicates := 0
+	for k, err := range errs {
+		if err == nil {
+			continue
+		}
+		// rows are reported by their place in the file
+		var repeated *db.DuplicateRowError
+		if errors.As(err, &repeated) {
+			err = &db.DuplicateRowError{Row: valid[repeated.Row] + 1}
+		}
+		markFailed(r, &report.Rows[valid[k]], err)
+		duplicates++
+	}
+	return duplicates, nil
+}
+
+// markFailed reports duplicates as invalid rows pointing to the existing riddle, anything else as a failed insert
+func markFailed(r *http.Request, row *models.ImportRow, err error) {
+	var repeated *db.DuplicateRowError
+	if errors.As(err, &repeated) {
+		row.Status = importInvalid
+		row.Errors = []string{fmt.Sprintf("Duplicate of row %d", repeated.Row)}
+		return
+	}
+
+	var duplicate *db.DuplicateError
+	if errors.As(err, &duplicate) {
+		row.Status = importInvalid
+		row.Errors = []string{fmt.Sprintf("Duplicate of riddle %d", duplicate.ID)}
+		if duplicate.Published {
+			row.Links = []models.Link{
+				{Rel: "duplicate", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", duplicate.ID))},
+			}
+		}
+		return
+	}
+
+	row.Status = importFailed
+	row.Errors = []string{"Error inserting riddle"}
+}
+
+// skipRows marks the rows that were fine but not stored because the atomic import was abandoned
+func skipRows(report *models.ImportReport) {
+	for i := range report.Rows {
+		if report.Rows[i].Status == importValid {
+			report.Rows[i].Status = importSkipped
+		}
+	}
+}
+
+func parseBoolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// parseImport picks the format from ?format=, the upload's content type or the uploaded file's extension
+func parseImport(r *http.Request) ([]parsedRow, error) {
+	format := r.URL.Query().Get("format")
+	body := io.Reader(r.Body)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New("Missing file field in upload")
+		}
+		defer file.Close()
+
+		body = file
+		mediaType, _, _ = mime.ParseMediaType(header.Header.Get("Content-Type"))
+		if format == "" {
+			format = formatFromExtension(header.Filename)
+		}
+	}
+
+	if format == "" {
+		format = formatFromMediaType(mediaType)
+	}
+
+	switch format {
+	case "json":
+		return parseJSONImport(body)
+	case "ndjson":
+		return parseNDJSONImport(body)
+	case "csv":
+		return parseCSVImport(body)
+	default:
+		return nil, errors.New("Unknown import format: use json, ndjson or csv")
+	}
+}
+
+func formatFromMediaType(mediaType string) string {
+	switch mediaType {
+	case "application/json":
+		return "json"
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return "ndjson"
+	case "text/csv":
+		return "csv"
+	}
+	return ""
+}
+
+func formatFromExtension(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	}
+	return ""
+}
+
+func parseJSONImport(body io.Reader) ([]parsedRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("Invalid JSON array: %v", err)
+	}
+
+	rows := make([]parsedRow, 0, len(raw))
+	for _, item := range raw {
+		rows = append(rows, decodeImportRecord(item))
+	}
+	return rows, nil
+}
+
+func parseNDJSONImport(body io.Reader) ([]parsedRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxImportBytes)
+
+	var rows []parsedRow
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rows = append(rows, decodeImportRecord(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Invalid NDJSON stream: %v", err)
+	}
+	return rows, nil
+}
+
+// decodeImportRecord reads a JSON row exactly like the body of POST /api/riddles
+func decodeImportRecord(data []byte) parsedRow {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return parsedRow{err: fmt.Errorf("Invalid JSON object: %v", err)}
+	}
+	riddle, err := riddleFromBody(doc, 0)
+	return parsedRow{riddle: riddle, err: err}
+}
+
+var importColumns = map[string]bool{
+	"riddle":     true,
+	"solution":   true,
+	"synonyms":   true,
+	"tags":       true,
+	"username":   true,
+	"user_email": true,
+}
+
+// exportOnlyColumns are written by the CSV export and ignored on import, so an export can be imported again
+var exportOnlyColumns = map[string]bool{
+	"id":               true,
+	"published":        true,
+	"date_created":     true,
+	"reviewed_by":      true,
+	"reviewed_at":      true,
+	"rejection_reason": true,
+}
+
+func parseCSVImport(body io.Reader) ([]parsedRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV header: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if exportOnlyColumns[name] {
+			continue
+		}
+		if !importColumns[name] {
+			return nil, fmt.Errorf("Unknown CSV column: %s", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["riddle"]; !ok {
+		return nil, errors.New("CSV header must include a riddle column")
+	}
+	if _, ok := columns["solution"]; !ok {
+		return nil, errors.New("CSV header must include a solution column")
+	}
+
+	var rows []parsedRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// a row with the wrong number of cells is reported, the rows after it are still read
+		if errors.Is(err, csv.ErrFieldCount) {
+			rows = append(rows, parsedRow{err: errors.New("Wrong number of CSV fields")})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+
+		cell := func(name string) string {
+			i, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		// the row becomes the document a JSON import would have sent, so it is read the same way
+		doc := map[string]interface{}{}
+		for name := range columns {
+			switch {
+			case name != "synonyms" && name != "tags":
+				doc[name] = cell(name)
+			case cell(name) != "":
+				doc[name] = splitCSVList(cell(name))
+			}
+		}
+		riddle, err := riddleFromBody(doc, 0)
+		rows = append(rows, parsedRow{riddle: riddle, err: err})
+	}
+	return rows, nil
+}
+
+func splitCSVList(cell string) []interface{} {
+	var values []interface{}
+	for _, value := range strings.Split(cell, "|") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeImportRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		row        string
+		wantErrors []string
+	}{
+		{"valid", `{"riddle": " What has keys? ", "solution": "piano", "synonyms": ["keyboard"], "tags": ["music"]}`, nil},
+		{"id", `{"id": 12, "riddle": "What has keys?", "solution": "piano"}`, []string{"id is assigned by the server"}},
+		{"unknown field", `{"riddle": "What has keys?", "solution": "piano", "answer": "piano"}`, []string{"answer is not a riddle field"}},
+		{"synonyms not a list", `{"riddle": "What has keys?", "solution": "piano", "synonyms": "keyboard"}`, []string{"synonyms must be a list of strings"}},
+		{"tags not strings", `{"riddle": "What has keys?", "solution": "piano", "tags": [1, 2]}`, []string{"tags must be a list of strings"}},
+		{"missing solution", `{"riddle": "What has keys?"}`, []string{"solution is required"}},
+		{"not an object", `["What has keys?", "piano"]`, []string{"A riddle must be a JSON object"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := decodeImportRecord([]byte(tt.row))
+			var got []string
+			if row.err != nil {
+				got = errorMessages(row.err)
+			}
+			if !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("errors = %q, want %q", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestParseCSVImport(t *testing.T) {
+	csv := "id,riddle,solution,synonyms,tags,username\n" +
+		"4, What has keys? ,piano,keyboard|organ,music,\n" +
+		"5,What has a neck?,,,,ana\n"
+
+	rows, err := parseCSVImport(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseCSVImport: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+
+	first := rows[0]
+	if first.err != nil {
+		t.Fatalf("row 1: %v", first.err)
+	}
+	if first.riddle.Riddle != "What has keys?" || first.riddle.Solution != "piano" || first.riddle.Username.Valid {
+		t.Errorf("row 1 = %+v", first.riddle)
+	}
+	if !reflect.DeepEqual(first.riddle.Synonyms, []string{"keyboard", "organ"}) || !reflect.DeepEqual(first.riddle.Tags, []string{"music"}) {
+		t.Errorf("row 1 synonyms, tags = %q, %q", first.riddle.Synonyms, first.riddle.Tags)
+	}
+
+	if got := errorMessages(rows[1].err); !reflect.DeepEqual(got, []string{"solution is required"}) {
+		t.Errorf("row 2 errors = %q", got)
+	}
+}
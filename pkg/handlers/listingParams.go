@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ionutinit/riddles-api/pkg/db"
+)
+
+const (
+	defaultPageSize = 20
+	// hard limit on ?limit=, larger values are clamped to it
+	maxPageSize = 100
+)
+
+// encodeCursor and decodeCursor keep the cursor opaque to clients, decodeCursor refuses keys that do not fit the sort of the cursor
+func encodeCursor(cursor *db.Cursor) string {
+	cursorJSON, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func decodeCursor(value string) (*db.Cursor, error) {
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor db.Cursor
+	if err := json.Unmarshal(cursorJSON, &cursor); err != nil {
+		return nil, err
+	}
+	if !db.ValidCursor(&cursor) {
+		return nil, fmt.Errorf("invalid key %q for sort %q", cursor.Key, cursor.Sort)
+	}
+	return &cursor, nil
+}
+
+// pageURL keeps the query of the current request, replacing only the cursor
+func pageURL(r *http.Request, cursor *db.Cursor, limit int) string {
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Del("cursor")
+	if cursor != nil {
+		query.Set("cursor", encodeCursor(cursor))
+	}
+	return constructURL(r, r.URL.Path+"?"+query.Encode())
+}
+
+// parseQueryTime accepts a plain date or a full RFC 3339 timestamp
+// a plain date used as an upper bound covers the whole day
+func parseQueryTime(value string, upperBound bool) (*time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	if upperBound {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, nil
+}
+
+// parseRiddleFilter reads the listing filters from the query string
+func parseRiddleFilter(r *http.Request) (db.RiddleFilter, error) {
+	query := r.URL.Query()
+	filter := db.RiddleFilter{Username: query.Get("username")}
+
+	if value := query.Get("tag"); value != "" {
+		tags, err := db.NormalizeTags([]string{value})
+		if err != nil {
+			return filter, err
+		}
+		filter.Tag = tags[0]
+	}
+
+	var err error
+	if value := query.Get("created_from"); value != "" {
+		if filter.CreatedFrom, err = parseQueryTime(value, false); err != nil {
+			return filter, fmt.Errorf("invalid created_from, expected YYYY-MM-DD or RFC 3339")
+		}
+	}
+	if value := query.Get("created_to"); value != "" {
+		if filter.CreatedBefore, err = parseQueryTime(value, true); err != nil {
+			return filter, fmt.Errorf("invalid created_to, expected YYYY-MM-DD or RFC 3339")
+		}
+	}
+	if value := query.Get("modified_since"); value != "" {
+		if filter.ModifiedSince, err = parseQueryTime(value, false); err != nil {
+			return filter, fmt.Errorf("invalid modified_since, expected YYYY-MM-DD or RFC 3339")
+		}
+	}
+
+	if value := query.Get("min_solution_length"); value != "" {
+		if filter.MinSolution, err = strconv.Atoi(value); err != nil || filter.MinSolution < 0 {
+			return filter, fmt.Errorf("invalid min_solution_length")
+		}
+	}
+	if value := query.Get("max_solution_length"); value != "" {
+		if filter.MaxSolution, err = strconv.Atoi(value); err != nil || filter.MaxSolution < 0 {
+			return filter, fmt.Errorf("invalid max_solution_length")
+		}
+	}
+
+	if value := query.Get("has_images"); value != "" {
+		hasImages, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid has_images, expected true or false")
+		}
+		filter.HasImages = &hasImages
+	}
+
+	return filter, nil
+}
+
+// parseListParams reads pagination, filters and sorting from the query string
+func parseListParams(r *http.Request) (db.ListParams, error) {
+	query := r.URL.Query()
+	params := db.ListParams{Limit: defaultPageSize, Sort: db.DefaultSort}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 {
+			return params, fmt.Errorf("invalid limit")
+		}
+		params.Limit = parsed
+		if params.Limit > maxPageSize {
+			params.Limit = maxPageSize
+		}
+	}
+
+	// the direction comes with the sort, -created or created:desc, ?order= is kept for the clients already using it
+	order := query.Get("order")
+	if sort := query.Get("sort"); sort != "" {
+		key, direction, found := strings.Cut(sort, ":")
+		if strings.HasPrefix(key, "-") && !found {
+			key, direction = strings.TrimPrefix(key, "-"), "desc"
+		}
+		if !db.IsSortKey(key) {
+			return params, fmt.Errorf("invalid sort, expected id, created, modified or popularity, optionally as -created or created:desc")
+		}
+		if direction != "" {
+			if order != "" && order != direction {
+				return params, fmt.Errorf("sort and order ask for different directions")
+			}
+			order = direction
+		}
+		params.Sort = key
+	}
+
+	switch order {
+	case "", "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return params, fmt.Errorf("invalid sort direction, expected asc or desc")
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil {
+			return params, fmt.Errorf("invalid cursor")
+		}
+		// a cursor only makes sense for the ordering it was created with
+		if decoded.Sort != params.Sort || decoded.Desc != params.Desc {
+			return params, fmt.Errorf("cursor does not match the requested sort order")
+		}
+		params.Cursor = decoded
+	}
+
+	filter, err := parseRiddleFilter(r)
+	if err != nil {
+		return params, err
+	}
+	params.Filter = filter
+
+	return params, nil
+}
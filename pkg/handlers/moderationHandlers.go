@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+type RejectionRequest struct {
+	Reason string `json:"reason"`
+}
+
+func GetPendingRiddlesHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetPendingRiddlesHandler")
+
+	riddles, err := db.ListPendingRiddles()
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GetPendingRiddlesHandler",
+		}).Error("Error querying pending riddles")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	ids := make([]int, 0, len(riddles))
+	for _, rdl := range riddles {
+		ids = append(ids, rdl.ID)
+	}
+
+	tags, ok := riddleTags(w, r, "GetPendingRiddlesHandler", ids...)
+	if !ok {
+		return
+	}
+
+	response := []models.AdminRiddle{}
+	for _, rdl := range riddles {
+		rdl.Tags = tags[rdl.ID]
+		rdl.Links = []models.Link{
+			{Rel: "approve", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/approve", rdl.ID))},
+			{Rel: "reject", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/reject", rdl.ID))},
+		}
+		response = append(response, rdl)
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"count":   len(response),
+		"handler": "GetPendingRiddlesHandler",
+	}).Info("Successfully executed GetPendingRiddlesHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenterFor(r).adminList(response))
+}
+
+func ApproveRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	reviewRiddle(w, r, true, "ApproveRiddleHandler")
+}
+
+func RejectRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	reviewRiddle(w, r, false, "RejectRiddleHandler")
+}
+
+func reviewRiddle(w http.ResponseWriter, r *http.Request, approve bool, handler string) {
+	logger.Log.Info("Executing " + handler)
+
+	id, ok := riddleIDFromPath(w, r, handler)
+	if !ok {
+		return
+	}
+
+	var rejection RejectionRequest
+	if !approve {
+		err := json.NewDecoder(r.Body).Decode(&rejection)
+		if err != nil && err != io.EOF {
+			logger.Log.WithFields(logrus.Fields{
+				"error":   err,
+				"handler": handler,
+			}).Error("Error decoding request body")
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+			return
+		}
+
+		if strings.TrimSpace(rejection.Reason) == "" {
+			problem.Write(w, r, problem.Validation("Missing required field: reason", problem.FieldError{Field: "reason", Message: "is required"}))
+			return
+		}
+	}
+
+	reviewer := editorFromRequest(r)
+	rowsAffected, err := db.ReviewRiddle(id, approve, reviewer, rejection.Reason)
+	if errors.Is(err, db.ErrAlreadyReviewed) {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": handler,
+		}).Warn("Riddle is not waiting for review")
+		p := problem.New(http.StatusConflict, problem.CodeConflict, "Riddle was already reviewed, only pending riddles can be approved or rejected")
+		p.Links = []models.Link{
+			{Rel: "pending", Href: constructURL(r, "/api/admin/riddles/pending")},
+		}
+		problem.Write(w, r, p)
+		return
+	}
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": handler,
+		}).Error("Error reviewing riddle")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error reviewing riddle")
+		return
+	}
+
+	if rowsAffected == 0 {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": handler,
+		}).Warn("ID not matching any riddle for review")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+		return
+	}
+
+	message := "Riddle approved successfully"
+	links := []models.Link{
+		{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		{Rel: "pending", Href: constructURL(r, "/api/admin/riddles/pending")},
+	}
+	if !approve {
+		message = "Riddle rejected successfully"
+		links = links[1:]
+	}
+
+	response := map[string]interface{}{
+		"message":  message,
+		"reviewer": reviewer,
+		"links":    links,
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":       id,
+		"approved": approve,
+		"reviewer": reviewer,
+		"handler":  handler,
+	}).Info("Successfully executed " + handler)
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
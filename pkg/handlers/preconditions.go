@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+// riddleETag is the strong validator of a riddle, "<id>-<version>" in v1 and "<id>-<version>-v2" in v2,
+// the two versions are different representations of the riddle and must not validate each other
+func riddleETag(r *http.Request, rdl models.RiddleBase) string {
+	return fmt.Sprintf(`"%d-%d%s"`, rdl.ID, rdl.Version, etagSuffix(r))
+}
+
+// etagSuffix tells the validators of the API versions apart, v1 keeps the tags it always had
+func etagSuffix(r *http.Request) string {
+	if version := apiversion.FromRequest(r); version != apiversion.V1 {
+		return fmt.Sprintf("-v%d", version)
+	}
+	return ""
+}
+
+// ifMatchVersions reads the versions of the riddle listed in If-Match, to be checked by the database when the change is written.
+// nil versions mean the change is unconditional, either because If-Match is missing or because it is "*".
+// Tags that are weak or belong to another riddle can never match, so they are left out and may leave the list empty.
+// The tags of either API version are accepted, they name the same version of the riddle.
+// When config requires If-Match and it is missing, a 428 is written and ok is false.
+func ifMatchVersions(w http.ResponseWriter, r *http.Request, id int, handler string) ([]int, bool) {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if strings.TrimSpace(header) == "" {
+		if config.AppConfig.Concurrency.RequireIfMatch {
+			logger.Log.WithFields(logrus.Fields{
+				"id":      id,
+				"handler": handler,
+			}).Warn("Missing If-Match header")
+			problem.Error(w, r, http.StatusPreconditionRequired, problem.CodePreconditionRequired,
+				"If-Match header is required, send the ETag of the riddle")
+			return nil, false
+		}
+		return nil, true
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		// If-Match uses the strong comparison, weak tags never match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		riddleID, version, found := strings.Cut(strings.Trim(tag, `"`), "-")
+		if !found || riddleID != strconv.Itoa(id) {
+			continue
+		}
+		version, apiVersion, _ := strings.Cut(version, "-")
+		if apiVersion != "" && apiVersion != fmt.Sprintf("v%d", apiversion.V2) {
+			continue
+		}
+		if v, err := strconv.Atoi(version); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, true
+}
+
+// versionMatches reports whether the riddle is at one of the versions returned by ifMatchVersions
+func versionMatches(versions []int, version int) bool {
+	if versions == nil {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func writePreconditionFailed(w http.ResponseWriter, r *http.Request, id int, handler string) {
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"handler": handler,
+	}).Warn("Riddle modified since the version in If-Match")
+	problem.Error(w, r, http.StatusPreconditionFailed, problem.CodePreconditionFailed,
+		"Riddle was modified since it was fetched, fetch it again and retry")
+}
+
+// listingETag is a weak validator of the listings, they change with any riddle of the catalog and, when filtered by has_images, any image
+func listingETag(r *http.Request, f db.Freshness) string {
+	return fmt.Sprintf(`W/"%d-%d-%d-%d-%d%s"`, f.LastModified.UnixMicro(), f.Riddles, f.Guesses, f.Images, f.LastImage, etagSuffix(r))
+}
+
+// notModified sets the validators of the response and reports whether the copy the client holds is still current,
+// in which case a 304 was written and the handler must stop. A zero lastModified sends no Last-Modified.
+// If-None-Match takes precedence over If-Modified-Since, as the ETag sees changes the date cannot.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	fresh := false
+	if header := strings.Join(r.Header.Values("If-None-Match"), ","); strings.TrimSpace(header) != "" {
+		// If-None-Match uses the weak comparison
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				fresh = true
+				break
+			}
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.IsZero() {
+		// the header only has a precision of seconds
+		fresh = !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if fresh {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return fresh
+}
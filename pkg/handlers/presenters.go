@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+)
+
+// presenter shapes the responses of an API version. Handlers share their logic across versions and build the v1 models,
+// the presenter of the version answering the request turns them into what goes on the wire.
+type presenter interface {
+	riddle(rdl models.RiddleResponse) interface{}
+	list(list models.RiddleList) interface{}
+	search(results SearchResponse) interface{}
+	adminRiddle(rdl models.AdminRiddle) interface{}
+	adminList(riddles []models.AdminRiddle) interface{}
+	duplicates(clusters []models.DuplicateCluster) interface{}
+}
+
+func presenterFor(r *http.Request) presenter {
+	if apiversion.FromRequest(r) == apiversion.V2 {
+		return v2Presenter{}
+	}
+	return v1Presenter{}
+}
+
+// v1Presenter serves the models as they are, v1 clients keep the shapes they were written against
+type v1Presenter struct{}
+
+func (v1Presenter) riddle(rdl models.RiddleResponse) interface{} {
+	return rdl
+}
+
+func (v1Presenter) list(list models.RiddleList) interface{} {
+	return list
+}
+
+func (v1Presenter) search(results SearchResponse) interface{} {
+	return results
+}
+
+func (v1Presenter) adminRiddle(rdl models.AdminRiddle) interface{} {
+	return rdl
+}
+
+func (v1Presenter) adminList(riddles []models.AdminRiddle) interface{} {
+	return riddles
+}
+
+func (v1Presenter) duplicates(clusters []models.DuplicateCluster) interface{} {
+	return clusters
+}
+
+type v2Presenter struct{}
+
+func (v2Presenter) riddle(rdl models.RiddleResponse) interface{} {
+	return riddleV2(rdl)
+}
+
+func (v2Presenter) list(list models.RiddleList) interface{} {
+	data := make([]models.RiddleV2, 0, len(list.Items))
+	for _, rdl := range list.Items {
+		data = append(data, riddleV2(rdl))
+	}
+	return models.ListV2{
+		Data:  data,
+		Meta:  models.ListMeta{Total: list.Total},
+		Links: nonNilLinks(list.Links),
+	}
+}
+
+func (v2Presenter) search(results SearchResponse) interface{} {
+	data := make([]models.SearchResultV2, 0, len(results.Items))
+	for _, result := range results.Items {
+		data = append(data, models.SearchResultV2{
+			RiddleV2: riddleV2(result.RiddleResponse),
+			Rank:     result.Rank,
+			Snippet:  result.Snippet,
+		})
+	}
+	return models.ListV2{
+		Data:  data,
+		Meta:  models.ListMeta{Total: len(data), Query: results.Query},
+		Links: nonNilLinks(results.Links),
+	}
+}
+
+func (v2Presenter) adminRiddle(rdl models.AdminRiddle) interface{} {
+	return adminRiddleV2(rdl)
+}
+
+func (v2Presenter) adminList(riddles []models.AdminRiddle) interface{} {
+	data := make([]models.AdminRiddleV2, 0, len(riddles))
+	for _, rdl := range riddles {
+		data = append(data, adminRiddleV2(rdl))
+	}
+	return models.ListV2{
+		Data:  data,
+		Meta:  models.ListMeta{Total: len(data)},
+		Links: []models.Link{},
+	}
+}
+
+func (v2Presenter) duplicates(clusters []models.DuplicateCluster) interface{} {
+	data := make([]models.DuplicateClusterV2, 0, len(clusters))
+	for _, cluster := range clusters {
+		riddles := make([]models.AdminRiddleV2, 0, len(cluster.Riddles))
+		for _, rdl := range cluster.Riddles {
+			riddles = append(riddles, adminRiddleV2(rdl))
+		}
+		data = append(data, models.DuplicateClusterV2{Exact: cluster.Exact, Riddles: riddles})
+	}
+	return models.ListV2{
+		Data:  data,
+		Meta:  models.ListMeta{Total: len(data)},
+		Links: []models.Link{},
+	}
+}
+
+// riddleV2 reads the riddle once hideSolution has run, an empty solution is a hidden one
+func riddleV2(rdl models.RiddleResponse) models.RiddleV2 {
+	v2 := models.RiddleV2{
+		ID:       rdl.ID,
+		Riddle:   rdl.Riddle,
+		Synonyms: []string{},
+		Tags:     []string{},
+		Status:   rdl.Status,
+		Warnings: rdl.Warnings,
+		Links:    nonNilLinks(rdl.Links),
+	}
+	if rdl.Solution != "" {
+		solution := rdl.Solution
+		v2.Solution = &solution
+	}
+	if rdl.Synonyms != nil {
+		v2.Synonyms = rdl.Synonyms
+	}
+	if rdl.Tags != nil {
+		v2.Tags = rdl.Tags
+	}
+	return v2
+}
+
+func adminRiddleV2(rdl models.AdminRiddle) models.AdminRiddleV2 {
+	return models.AdminRiddleV2{
+		RiddleV2:        riddleV2(models.RiddleResponse{RiddleBase: rdl.RiddleBase, Tags: rdl.Tags, Links: rdl.Links}),
+		Username:        rdl.Username,
+		UserEmail:       rdl.UserEmail,
+		Published:       rdl.Published,
+		DateCreated:     rdl.DateCreated,
+		ReviewedBy:      rdl.ReviewedBy,
+		ReviewedAt:      rdl.ReviewedAt,
+		RejectionReason: rdl.RejectionReason,
+		DeletedAt:       rdl.DeletedAt,
+	}
+}
+
+func nonNilLinks(links []models.Link) []models.Link {
+	if links == nil {
+		return []models.Link{}
+	}
+	return links
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+type RevisionDiffResponse struct {
+	From    int                  `json:"from"`
+	To      int                  `json:"to"`
+	Changes []models.FieldChange `json:"changes"`
+	Links   []models.Link        `json:"links,omitempty"`
+}
+
+// diffRevisions lists the fields that differ between two revisions
+func diffRevisions(from models.Revision, to models.Revision) []models.FieldChange {
+	fields := []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"riddle", from.Riddle, to.Riddle},
+		{"solution", from.Solution, to.Solution},
+		{"synonyms", from.Synonyms, to.Synonyms},
+		{"username", from.Username, to.Username},
+		{"user_email", from.UserEmail, to.UserEmail},
+		{"tags", from.Tags, to.Tags},
+	}
+
+	changes := []models.FieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.from, field.to) {
+			changes = append(changes, models.FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	return changes
+}
+
+func revisionLinks(r *http.Request, id int, revision int) []models.Link {
+	return []models.Link{
+		{Rel: "revert", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/revisions/%d/revert", id, revision))},
+	}
+}
+
+func GetRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetRevisionsHandler")
+
+	id, ok := riddleIDFromPath(w, r, "GetRevisionsHandler")
+	if !ok {
+		return
+	}
+
+	if !riddleExistsOrError(w, r, id, "GetRevisionsHandler") {
+		return
+	}
+
+	revisions, err := db.ListRevisions(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "GetRevisionsHandler",
+		}).Error("Error querying riddle revisions")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	response := []models.Revision{}
+	for _, rev := range revisions {
+		rev.Links = revisionLinks(r, id, rev.Revision)
+		response = append(response, rev)
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"count":   len(response),
+		"handler": "GetRevisionsHandler",
+	}).Info("Successfully executed GetRevisionsHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadRevision writes a 404 or 500 when the revision cannot be loaded
+func loadRevision(w http.ResponseWriter, r *http.Request, id int, revision int, handler string) (models.Revision, bool) {
+	rev, err := db.GetRevision(id, revision)
+	if err == sql.ErrNoRows {
+		logger.Log.WithFields(logrus.Fields{
+			"id":       id,
+			"revision": revision,
+			"handler":  handler,
+		}).Warn("Revision not found")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, fmt.Sprintf("Revision %d not found", revision))
+		return rev, false
+	}
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":       id,
+			"revision": revision,
+			"error":    err,
+			"handler":  handler,
+		}).Error("Error querying riddle revision")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return rev, false
+	}
+	return rev, true
+}
+
+func DiffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing DiffRevisionsHandler")
+
+	id, ok := riddleIDFromPath(w, r, "DiffRevisionsHandler")
+	if !ok {
+		return
+	}
+
+	from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+	to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+	if errFrom != nil || errTo != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid revisions, expected ?from= and ?to= revision numbers")
+		return
+	}
+
+	fromRevision, ok := loadRevision(w, r, id, from, "DiffRevisionsHandler")
+	if !ok {
+		return
+	}
+
+	toRevision, ok := loadRevision(w, r, id, to, "DiffRevisionsHandler")
+	if !ok {
+		return
+	}
+
+	response := RevisionDiffResponse{
+		From:    from,
+		To:      to,
+		Changes: diffRevisions(fromRevision, toRevision),
+		Links:   revisionLinks(r, id, from),
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"from":    from,
+		"to":      to,
+		"handler": "DiffRevisionsHandler",
+	}).Info("Successfully executed DiffRevisionsHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func RevertRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing RevertRevisionHandler")
+
+	id, ok := riddleIDFromPath(w, r, "RevertRevisionHandler")
+	if !ok {
+		return
+	}
+
+	revision, ok := intParamFromPath(w, r, "RevertRevisionHandler", "rev")
+	if !ok {
+		return
+	}
+
+	if _, ok := loadRevision(w, r, id, revision, "RevertRevisionHandler"); !ok {
+		return
+	}
+
+	if err := db.RevertRiddle(id, revision, editorFromRequest(r)); err != nil {
+		var duplicate *db.DuplicateError
+		if errors.As(err, &duplicate) {
+			logger.Log.WithFields(logrus.Fields{
+				"id":          id,
+				"revision":    revision,
+				"duplicateOf": duplicate.ID,
+				"handler":     "RevertRevisionHandler",
+			}).Warn("Rejected revert duplicating another riddle")
+			problem.Write(w, r, duplicateProblem(r, duplicate))
+			return
+		}
+		p := revertProblem(r, id, err)
+		entry := logger.Log.WithFields(logrus.Fields{
+			"id":       id,
+			"revision": revision,
+			"error":    err,
+			"handler":  "RevertRevisionHandler",
+		})
+		if p.Status >= http.StatusInternalServerError {
+			entry.Error("Error reverting riddle")
+		} else {
+			entry.Warn("Riddle cannot be reverted")
+		}
+		problem.Write(w, r, p)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("Riddle reverted to revision %d successfully", revision),
+		"links": []models.Link{
+			{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+			{Rel: "revisions", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/revisions", id))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":       id,
+		"revision": revision,
+		"handler":  "RevertRevisionHandler",
+	}).Info("Successfully executed RevertRevisionHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// revertProblem answers a failed revert, a riddle in the trash has to be restored before it can be reverted
+func revertProblem(r *http.Request, id int, err error) *problem.Problem {
+	if errors.Is(err, db.ErrRiddleInTrash) {
+		return trashProblem(r, id, "Riddle is in the trash, restore it before reverting it")
+	}
+	p := problem.FromError(err)
+	if p.Status == http.StatusNotFound {
+		p.Detail = "Riddle not found"
+	}
+	if p.Status == http.StatusInternalServerError {
+		p.Detail = "Error reverting riddle"
+	}
+	return p
+}
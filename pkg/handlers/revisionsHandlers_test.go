@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+func TestRevertProblem(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantRestore bool
+	}{
+		{"in trash", db.ErrRiddleInTrash, http.StatusConflict, problem.CodeConflict, true},
+		{"missing", sql.ErrNoRows, http.StatusNotFound, problem.CodeNotFound, false},
+		{"missing wrapped", fmt.Errorf("locking riddle: %w", sql.ErrNoRows), http.StatusNotFound, problem.CodeNotFound, false},
+		{"database down", errors.New("connection refused"), http.StatusInternalServerError, problem.CodeInternal, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/riddles/7/revisions/2/revert", nil)
+			p := revertProblem(r, 7, tt.err)
+
+			if p.Status != tt.wantStatus || p.Code != tt.wantCode {
+				t.Errorf("problem = %d %s, want %d %s", p.Status, p.Code, tt.wantStatus, tt.wantCode)
+			}
+			restore := len(p.Links) == 1 && p.Links[0].Rel == "restore" && p.Links[0].Href == "http://example.com/api/admin/riddles/7/restore"
+			if restore != tt.wantRestore {
+				t.Errorf("links = %v, want restore link: %v", p.Links, tt.wantRestore)
+			}
+		})
+	}
+}
@@ -2,98 +2,134 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/ionutinit/riddles-api/models"
 	"github.com/ionutinit/riddles-api/pkg/db"
 	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
 )
 
 func GetAllRiddlesHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("Executing GetAllRiddlesHandler")
-	database := db.GetDB()
 
-	rows, err := database.Query("SELECT id, riddle, solution, synonyms FROM riddles WHERE published = TRUE")
+	params, err := parseListParams(r)
 	if err != nil {
 		logger.Log.WithFields(logrus.Fields{
+			"query":   r.URL.RawQuery,
 			"error":   err,
 			"handler": "GetAllRiddlesHandler",
-		})
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}).Warn("Invalid listing parameters")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
 		return
 	}
-	defer rows.Close()
-
-	var riddles []models.RiddleBase
-	for rows.Next() {
-		var rdl models.RiddleBase
-		if err := rows.Scan(&rdl.ID, &rdl.Riddle, &rdl.Solution, &rdl.Synonyms); err != nil {
-			logger.Log.WithFields(logrus.Fields{
-				"error":   err,
-				"handler": "GetAllRiddlesHandler",
-			}).Error("Error scanning riddles table rows")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		riddles = append(riddles, rdl)
+
+	// the catalog is checked first, so an unchanged listing is answered without running it
+	freshness, err := db.ListingFreshness(params.Sort == "popularity", params.Filter.HasImages != nil)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GetAllRiddlesHandler",
+		}).Error("Error checking listing freshness")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+	// guesses reorder the popularity listing and images change what has_images matches without touching any riddle,
+	// so only the ETag can tell these listings changed
+	lastModified := freshness.LastModified
+	if params.Sort == "popularity" || params.Filter.HasImages != nil {
+		lastModified = time.Time{}
+	}
+	if notModified(w, r, listingETag(r, freshness), lastModified) {
+		return
 	}
 
-	if err = rows.Err(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	page, err := db.ListRiddles(params)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GetAllRiddlesHandler",
+		}).Error("Error querying riddles")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
 		return
 	}
 
-	var riddlesResponse []models.RiddleResponse
-	for _, rdlBase := range riddles {
+	riddlesResponse := models.RiddleList{
+		Items: []models.RiddleResponse{},
+		Total: page.Total,
+		Links: []models.Link{
+			{Rel: "self", Href: pageURL(r, params.Cursor, params.Limit)},
+		},
+	}
+
+	if page.Next != nil {
+		riddlesResponse.Links = append(riddlesResponse.Links, models.Link{Rel: "next", Href: pageURL(r, page.Next, params.Limit)})
+	}
+	if page.Prev != nil {
+		riddlesResponse.Links = append(riddlesResponse.Links, models.Link{Rel: "prev", Href: pageURL(r, page.Prev, params.Limit)})
+	}
+
+	ids := make([]int, 0, len(page.Riddles))
+	for _, rdlBase := range page.Riddles {
+		ids = append(ids, rdlBase.ID)
+	}
+
+	tags, ok := riddleTags(w, r, "GetAllRiddlesHandler", ids...)
+	if !ok {
+		return
+	}
+
+	for _, rdlBase := range page.Riddles {
+		hideSolution(r, &rdlBase)
 		rdlResponse := models.RiddleResponse{
 			RiddleBase: rdlBase,
-			Links: []models.Link{
+			Tags:       tags[rdlBase.ID],
+			Links: append([]models.Link{
 				{Rel: "self", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
-			},
+			}, playLinks(r, rdlBase.ID)...),
 		}
-		riddlesResponse = append(riddlesResponse, rdlResponse)
+		riddlesResponse.Items = append(riddlesResponse.Items, rdlResponse)
 	}
 
 	logger.Log.WithFields(logrus.Fields{
-		"count": len(riddles),
+		"count": len(page.Riddles),
+		"total": page.Total,
 	}).Info("Successful query for GetAllRiddlesHandler")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(riddlesResponse)
+	json.NewEncoder(w).Encode(presenterFor(r).list(riddlesResponse))
 }
 
 func PostRiddleHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("Executing PostRiddleHandler")
 
-	var riddle models.Riddle
-	err := json.NewDecoder(r.Body).Decode(&riddle)
-	if err != nil {
-		logger.Log.WithFields(logrus.Fields{
-			"error":   err,
-			"handler": "PostRiddleHandler",
-		}).Error("Error decoding request body")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	riddle, ok := decodeRiddle(w, r, 0, "PostRiddleHandler")
+	if !ok {
 		return
 	}
 
-	if riddle.Riddle == "" || riddle.Solution == "" {
+	id, err := db.InsertNewRiddle(riddle, editorFromRequest(r))
+	var duplicate *db.DuplicateError
+	if errors.As(err, &duplicate) {
 		logger.Log.WithFields(logrus.Fields{
-			"handler": "PostRiddleHandler",
-		}).Warn("Missing required fields in request")
-		http.Error(w, "Missing required fields: riddle or solution", http.StatusBadRequest)
+			"duplicateOf": duplicate.ID,
+			"handler":     "PostRiddleHandler",
+		}).Warn("Rejected duplicate riddle")
+		problem.Write(w, r, duplicateProblem(r, duplicate))
 		return
 	}
-
-	id, err := db.InsertNewRiddle(riddle)
 	if err != nil {
 		logger.Log.WithFields(logrus.Fields{
 			"error":   err,
 			"handler": "PostRiddleHandler",
 		}).Error("Error inserting new riddle in the database")
-		http.Error(w, "Error inserting new riddle", http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error inserting new riddle")
 		return
 	}
 
@@ -104,8 +140,13 @@ func PostRiddleHandler(w http.ResponseWriter, r *http.Request) {
 			Solution: riddle.Solution,
 			Synonyms: riddle.Synonyms,
 		},
+		Tags:     riddle.Tags,
+		Status:   "pending",
+		Warnings: duplicateWarnings(r, riddle, id),
+		// the riddle is not public until it is approved, so there is nothing to view yet, only the review to act on
 		Links: []models.Link{
-			{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+			{Rel: "approve", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/approve", id))},
+			{Rel: "reject", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/reject", id))},
 			{Rel: "patch", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
 			{Rel: "delete", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
 		},
@@ -117,5 +158,111 @@ func PostRiddleHandler(w http.ResponseWriter, r *http.Request) {
 	}).Info("Succesfully executed PostRiddleHandler")
 
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(riddleResponse)
+	json.NewEncoder(w).Encode(presenterFor(r).riddle(riddleResponse))
+}
+
+// duplicateProblem is the 409 for a riddle refused as a duplicate, linking to the existing one when it is public
+func duplicateProblem(r *http.Request, duplicate *db.DuplicateError) *problem.Problem {
+	if !duplicate.Published {
+		return problem.New(http.StatusConflict, problem.CodeDuplicate, "Riddle was already submitted and is not public")
+	}
+
+	p := problem.New(http.StatusConflict, problem.CodeDuplicate, fmt.Sprintf("Riddle already exists as riddle %d", duplicate.ID))
+	p.Links = []models.Link{
+		{Rel: "duplicate", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", duplicate.ID))},
+	}
+	return p
+}
+
+// decodeRiddle reads a whole riddle from the body, the same way for POST and PUT with riddleFromBody.
+// id is the riddle being replaced, or 0 for a new one. It writes the error response itself, so callers only need to return when ok is false.
+func decodeRiddle(w http.ResponseWriter, r *http.Request, id int, handler string) (models.Riddle, bool) {
+	var doc map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil || doc == nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": handler,
+		}).Error("Error decoding request body")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+		return models.Riddle{}, false
+	}
+
+	riddle, err := riddleFromBody(doc, id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": handler,
+		}).Warn("Invalid riddle in request")
+		problem.Write(w, r, problem.FromError(err))
+		return models.Riddle{}, false
+	}
+	return riddle, true
+}
+
+// riddleFromBody reads a riddle sent by a client, for POST, PUT and every imported row: riddleFromDocument refuses
+// unknown fields and trims the text, then validateRiddle checks it. id is the riddle being replaced, or 0 for a new one.
+func riddleFromBody(doc interface{}, id int) (models.Riddle, error) {
+	// documents fetched from the API carry their id, it has to be the one being replaced, new riddles get theirs from the server
+	if object, ok := doc.(map[string]interface{}); ok {
+		if bodyID, ok := object["id"]; ok {
+			if number, isNumber := bodyID.(float64); id == 0 || !isNumber || number != float64(id) {
+				message := "does not match the riddle in the path"
+				if id == 0 {
+					message = "is assigned by the server"
+				}
+				return models.Riddle{}, problem.Validation("Invalid riddle", problem.FieldError{Field: "id", Message: message})
+			}
+			delete(object, "id")
+		}
+	}
+
+	riddle, err := riddleFromDocument(doc)
+	if err == nil {
+		err = validateRiddle(&riddle)
+	}
+	return riddle, err
+}
+
+// maxColumnLength is the size of the VARCHAR columns of a riddle
+const maxColumnLength = 255
+
+// validateRiddle checks the required fields and their lengths and normalizes synonyms and tags,
+// it is shared by every way of creating riddles. The error is a validation problem listing every invalid field.
+func validateRiddle(riddle *models.Riddle) error {
+	var fields []problem.FieldError
+	if riddle.Riddle == "" {
+		fields = append(fields, problem.FieldError{Field: "riddle", Message: "is required"})
+	}
+	if riddle.Solution == "" {
+		fields = append(fields, problem.FieldError{Field: "solution", Message: "is required"})
+	}
+
+	// the columns are VARCHAR(255), a longer value would only fail at the INSERT
+	tooLong := fmt.Sprintf("must be at most %d characters", maxColumnLength)
+	for _, column := range []struct {
+		field string
+		value string
+	}{
+		{"solution", riddle.Solution},
+		{"username", riddle.Username.String},
+		{"user_email", riddle.UserEmail.String},
+	} {
+		if utf8.RuneCountInString(column.value) > maxColumnLength {
+			fields = append(fields, problem.FieldError{Field: column.field, Message: tooLong})
+		}
+	}
+
+	riddle.Synonyms = db.NormalizeSynonyms(riddle.Synonyms)
+
+	tags, err := db.NormalizeTags(riddle.Tags)
+	if err != nil {
+		fields = append(fields, problem.FieldError{Field: "tags", Message: err.Error()})
+	}
+	riddle.Tags = tags
+
+	if len(fields) > 0 {
+		return problem.Validation("Invalid riddle", fields...)
+	}
+	return nil
 }
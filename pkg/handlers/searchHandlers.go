@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+type SearchResponse struct {
+	Query string                `json:"query"`
+	Items []models.SearchResult `json:"items"`
+	Links []models.Link         `json:"links,omitempty"`
+}
+
+func SearchRiddlesHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing SearchRiddlesHandler")
+
+	query := r.URL.Query()
+	excludeSolutions, _ := strconv.ParseBool(query.Get("exclude_solutions"))
+
+	tsquery := db.SearchQuery(query.Get("q"), excludeSolutions)
+	if tsquery == "" {
+		logger.Log.WithFields(logrus.Fields{
+			"q":       query.Get("q"),
+			"handler": "SearchRiddlesHandler",
+		}).Warn("Missing search terms")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "Missing required query parameter: q")
+		return
+	}
+
+	limit := defaultPageSize
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid limit")
+			return
+		}
+		limit = parsed
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+	}
+
+	results, err := db.SearchRiddles(tsquery, limit)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"tsquery": tsquery,
+			"error":   err,
+			"handler": "SearchRiddlesHandler",
+		}).Error("Error searching riddles")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	response := SearchResponse{
+		Query: query.Get("q"),
+		Items: []models.SearchResult{},
+		Links: []models.Link{
+			{Rel: "self", Href: constructURL(r, "/api/riddles/search?"+r.URL.RawQuery)},
+		},
+	}
+
+	for _, result := range results {
+		hideSolution(r, &result.RiddleBase)
+		result.Links = append([]models.Link{
+			{Rel: "self", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", result.ID))},
+		}, playLinks(r, result.ID)...)
+		response.Items = append(response.Items, result)
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"tsquery": tsquery,
+		"count":   len(results),
+		"handler": "SearchRiddlesHandler",
+	}).Info("Successfully executed SearchRiddlesHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenterFor(r).search(response))
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/config"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/router"
+)
+
+type SessionResponse struct {
+	Session models.PlaySession `json:"session"`
+	// the riddle as shaped by the presenter of the API version
+	Riddle  interface{}   `json:"riddle,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Links   []models.Link `json:"links,omitempty"`
+}
+
+func sessionTTL() time.Duration {
+	hours := config.AppConfig.Sessions.TTLHours
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func PostSessionHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing PostSessionHandler")
+
+	session, err := db.CreateSession(sessionTTL())
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "PostSessionHandler",
+		}).Error("Error creating play session")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error creating session")
+		return
+	}
+
+	response := SessionResponse{
+		Session: session,
+		Links: []models.Link{
+			{Rel: "next", Href: constructURL(r, fmt.Sprintf("/api/sessions/%s/next", session.ID))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"session": session.ID,
+		"total":   session.Total,
+		"handler": "PostSessionHandler",
+	}).Info("Successfully executed PostSessionHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func NextSessionRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing NextSessionRiddleHandler")
+
+	sessionID := router.Param(r, "id")
+
+	for {
+		riddleID, session, err := db.AdvanceSession(sessionID, sessionTTL())
+		switch {
+		case errors.Is(err, db.ErrSessionNotFound):
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Session not found")
+			return
+		case errors.Is(err, db.ErrSessionExpired):
+			problem.Error(w, r, http.StatusGone, problem.CodeGone, "Session expired")
+			return
+		case errors.Is(err, db.ErrSessionExhausted):
+			response := map[string]interface{}{
+				"message": "Session exhausted, every riddle has been served",
+				"links": []models.Link{
+					{Rel: "new-session", Href: constructURL(r, "/api/sessions")},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+			return
+		case err != nil:
+			logger.Log.WithFields(logrus.Fields{
+				"session": sessionID,
+				"error":   err,
+				"handler": "NextSessionRiddleHandler",
+			}).Error("Error advancing play session")
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+			return
+		}
+
+		rdlBase, err := db.GetPublishedRiddle(riddleID)
+		if err == sql.ErrNoRows {
+			// the riddle was unpublished after the session was shuffled, skip it
+			continue
+		}
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"session": sessionID,
+				"id":      riddleID,
+				"error":   err,
+				"handler": "NextSessionRiddleHandler",
+			}).Error("Error scanning riddles table rows")
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+			return
+		}
+
+		hideSolution(r, &rdlBase)
+		response := SessionResponse{
+			Session: session,
+			Riddle: presenterFor(r).riddle(models.RiddleResponse{
+				RiddleBase: rdlBase,
+				Links: append([]models.Link{
+					{Rel: "self", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
+				}, playLinks(r, rdlBase.ID)...),
+			}),
+			Links: []models.Link{
+				{Rel: "next", Href: constructURL(r, fmt.Sprintf("/api/sessions/%s/next", session.ID))},
+			},
+		}
+
+		logger.Log.WithFields(logrus.Fields{
+			"session":  session.ID,
+			"position": session.Position,
+			"riddleID": rdlBase.ID,
+			"handler":  "NextSessionRiddleHandler",
+		}).Info("Successfully executed NextSessionRiddleHandler")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+}
@@ -1,65 +1,58 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/config"
 	"github.com/ionutinit/riddles-api/pkg/db"
 	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/patch"
+	"github.com/ionutinit/riddles-api/pkg/problem"
 )
 
 func GetRiddleByIdHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("Executing GetRiddleByIdHandler")
 
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) != 4 {
-		logger.Log.WithFields(logrus.Fields{
-			"path":    path,
-			"handler": "GetRiddleByIdHandler",
-		}).Error("Invalid request path")
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	id, ok := riddleIDFromPath(w, r, "GetRiddleByIdHandler")
+	if !ok {
 		return
 	}
 
-	id, err := strconv.Atoi(parts[3])
+	// unpublished riddles are either waiting for review or rejected, neither is public
+	rdlBase, err := db.GetPublishedRiddle(id)
 	if err != nil {
-		logger.Log.WithFields(logrus.Fields{
-			"path":    path,
-			"error":   err,
-			"handler": "GetRiddleByIdHandler",
-		}).Error("Invalid riddle ID in path")
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeRiddleError(w, r, id, err, "GetRiddleByIdHandler")
 		return
 	}
 
-	database := db.GetDB()
-
-	row := database.QueryRow("SELECT id, riddle, solution, synonyms FROM riddles WHERE id = $1", id)
+	if notModified(w, r, riddleETag(r, rdlBase), rdlBase.LastModified) {
+		return
+	}
 
-	var rdlBase models.RiddleBase
-	if err := row.Scan(&rdlBase.ID, &rdlBase.Riddle, &rdlBase.Solution, &rdlBase.Synonyms); err != nil {
-		logger.Log.WithFields(logrus.Fields{
-			"id":      id,
-			"error":   err,
-			"handler": "GetRiddleByIdHandler",
-		}).Error("Error scanning riddles table rows")
-		http.Error(w, "Riddle not found", http.StatusNotFound)
+	tags, ok := riddleTags(w, r, "GetRiddleByIdHandler", rdlBase.ID)
+	if !ok {
 		return
 	}
 
+	hideSolution(r, &rdlBase)
 	rdlResponse := models.RiddleResponse{
 		RiddleBase: rdlBase,
-		Links: []models.Link{
+		Tags:       tags[rdlBase.ID],
+		Links: append([]models.Link{
 			{Rel: "update", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
 			{Rel: "delete", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
-		},
+		}, playLinks(r, rdlBase.ID)...),
 	}
 
 	logger.Log.WithFields(logrus.Fields{
@@ -68,31 +61,84 @@ func GetRiddleByIdHandler(w http.ResponseWriter, r *http.Request) {
 	}).Info("Successfully executed GetRiddleByIdHandler")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(rdlResponse)
+	json.NewEncoder(w).Encode(presenterFor(r).riddle(rdlResponse))
+}
+
+func RevealSolutionHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing RevealSolutionHandler")
+
+	id, ok := riddleIDFromPath(w, r, "RevealSolutionHandler")
+	if !ok {
+		return
+	}
+
+	rdlBase, err := db.GetPublishedRiddle(id)
+	if err != nil {
+		writeRiddleError(w, r, id, err, "RevealSolutionHandler")
+		return
+	}
+
+	if notModified(w, r, riddleETag(r, rdlBase), rdlBase.LastModified) {
+		return
+	}
+
+	rdlResponse := models.RiddleResponse{
+		RiddleBase: rdlBase,
+		Links: []models.Link{
+			{Rel: "riddle", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
+			{Rel: "random", Href: constructURL(r, "/api/riddles/random")},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"handler": "RevealSolutionHandler",
+	}).Info("Successfully executed RevealSolutionHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenterFor(r).riddle(rdlResponse))
 }
 
 func RandomRiddleHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("Executing RandomRiddleHandler")
-	database := db.GetDB()
 
-	row := database.QueryRow("SELECT id, riddle, solution, synonyms FROM riddles WHERE published = TRUE ORDER BY RANDOM() LIMIT 1")
+	var filter db.RiddleFilter
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		tags, err := db.NormalizeTags([]string{tag})
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+			return
+		}
+		filter.Tag = tags[0]
+	}
 
-	var rdlBase models.RiddleBase
-	if err := row.Scan(&rdlBase.ID, &rdlBase.Riddle, &rdlBase.Solution, &rdlBase.Synonyms); err != nil {
+	rdlBase, err := db.RandomRiddle(filter)
+	if err == sql.ErrNoRows {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "No riddle matching the request")
+		return
+	}
+	if err != nil {
 		logger.Log.WithFields(logrus.Fields{
 			"error":   err,
 			"handler": "RandomRiddleHandler",
 		}).Error("Error scanning riddles table rows")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	tags, ok := riddleTags(w, r, "RandomRiddleHandler", rdlBase.ID)
+	if !ok {
 		return
 	}
 
+	hideSolution(r, &rdlBase)
 	rdlResponse := models.RiddleResponse{
 		RiddleBase: rdlBase,
-		Links: []models.Link{
+		Tags:       tags[rdlBase.ID],
+		Links: append([]models.Link{
 			{Rel: "update", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
 			{Rel: "delete", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", rdlBase.ID))},
-		},
+		}, playLinks(r, rdlBase.ID)...),
 	}
 
 	logger.Log.WithFields(logrus.Fields{
@@ -100,42 +146,34 @@ func RandomRiddleHandler(w http.ResponseWriter, r *http.Request) {
 	}).Info("Successful query for RandomRiddleHandler")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(rdlResponse)
+	json.NewEncoder(w).Encode(presenterFor(r).riddle(rdlResponse))
 }
 
 func DeleteRiddleHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("Executing DeleteRiddleHandler")
 
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) != 4 {
-		logger.Log.WithFields(logrus.Fields{
-			"path":    path,
-			"handler": "DeleteRiddleHandler",
-		}).Error("Invalid request path")
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	id, ok := riddleIDFromPath(w, r, "DeleteRiddleHandler")
+	if !ok {
 		return
 	}
 
-	id, err := strconv.Atoi(parts[3])
-	if err != nil {
-		logger.Log.WithFields(logrus.Fields{
-			"path":    path,
-			"error":   err,
-			"handler": "DeleteRiddleHandler",
-		}).Error("Invalid riddle ID")
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+	versions, ok := ifMatchVersions(w, r, id, "DeleteRiddleHandler")
+	if !ok {
 		return
 	}
 
-	rowsAffected, err := db.DeleteRiddle(id)
+	rowsAffected, err := db.DeleteRiddle(id, versions)
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, r, id, "DeleteRiddleHandler")
+		return
+	}
 	if err != nil {
 		logger.Log.WithFields(logrus.Fields{
 			"id":      id,
 			"error":   err,
 			"handler": "DeleteRiddleHandler",
 		}).Error("Error deleting riddle from database")
-		http.Error(w, "Error deleting riddle", http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error deleting riddle")
 		return
 	}
 
@@ -144,13 +182,14 @@ func DeleteRiddleHandler(w http.ResponseWriter, r *http.Request) {
 			"id":      id,
 			"handler": "DeleteRiddleHandler",
 		}).Warn("ID not matching any riddle for deletion")
-		http.Error(w, "Invalid ID", http.StatusNotFound)
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
 		return
 	}
 
 	response := map[string]interface{}{
-		"message": "Riddle deleted successfully",
+		"message": "Riddle moved to trash successfully",
 		"links": []models.Link{
+			{Rel: "restore", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/restore", id))},
 			{Rel: "all-riddles", Href: constructURL(r, "/api/riddles")},
 		},
 	}
@@ -160,99 +199,377 @@ func DeleteRiddleHandler(w http.ResponseWriter, r *http.Request) {
 		"handler": "DeleteRiddleHandler",
 	}).Info("Successfully executed DeleteRiddleHandler")
 
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
+// PatchRiddleHandler accepts a JSON Merge Patch, where null clears a field, or a JSON Patch.
+// Plain application/json bodies are treated as merge patches. The patched riddle is validated like a new one.
 func PatchRiddleHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("Executing PatchRiddleHandler")
 
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) != 4 {
-		logger.Log.WithFields(logrus.Fields{
-			"path":    path,
-			"handler": "PatchRiddleHandler",
-		}).Error("Invalid request path")
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	id, ok := riddleIDFromPath(w, r, "PatchRiddleHandler")
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "application/json" && mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType {
+		w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		problem.Error(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+			fmt.Sprintf("Unsupported patch format, use %s or %s", patch.MergePatchType, patch.JSONPatchType))
 		return
 	}
 
-	id, err := strconv.Atoi(parts[3])
+	versions, ok := ifMatchVersions(w, r, id, "PatchRiddleHandler")
+	if !ok {
+		return
+	}
+
+	current, err := db.GetAdminRiddle(id)
+	if err != nil {
+		writeRiddleError(w, r, id, err, "PatchRiddleHandler")
+		return
+	}
+	// preconditions come before the patch is even looked at, the update checks the version again
+	if !versionMatches(versions, current.Version) {
+		writePreconditionFailed(w, r, id, "PatchRiddleHandler")
+		return
+	}
+
+	var patched interface{}
+	if mediaType == patch.JSONPatchType {
+		var operations []patch.Operation
+		if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":   err,
+				"handler": "PatchRiddleHandler",
+			}).Error("Error decoding request body")
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+			return
+		}
+
+		patched, err = patch.Apply(riddleDocument(current), operations)
+		var patchErr *patch.Error
+		if errors.As(err, &patchErr) {
+			logger.Log.WithFields(logrus.Fields{
+				"id":      id,
+				"error":   err,
+				"handler": "PatchRiddleHandler",
+			}).Warn("Error applying JSON patch")
+			// a failed test means the riddle is not in the state the client expected
+			if patchErr.Op == "test" {
+				problem.Error(w, r, http.StatusConflict, problem.CodeConflict, err.Error())
+				return
+			}
+			problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeUnprocessable, err.Error())
+			return
+		}
+	} else {
+		var mergePatch interface{}
+		if err := json.NewDecoder(r.Body).Decode(&mergePatch); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":   err,
+				"handler": "PatchRiddleHandler",
+			}).Error("Error decoding request body")
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+			return
+		}
+		if _, ok := mergePatch.(map[string]interface{}); !ok {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Merge patch must be a JSON object")
+			return
+		}
+
+		patched = patch.Merge(riddleDocument(current), mergePatch)
+	}
+
+	updatedRiddle, err := riddleFromDocument(patched)
+	if err == nil {
+		err = validateRiddle(&updatedRiddle)
+	}
 	if err != nil {
 		logger.Log.WithFields(logrus.Fields{
-			"path":    path,
+			"id":      id,
 			"error":   err,
 			"handler": "PatchRiddleHandler",
-		}).Error("Invalid riddle ID")
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		}).Warn("Invalid riddle after patch")
+		problem.Write(w, r, problem.FromError(err))
 		return
 	}
 
-	// creating a map in order to check that only allowed fields exist in the request body
-	// more efficient than creating a json directly and then searching through it, is not efficient and it involves further parsing
-	var requestBodyMap map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&requestBodyMap); err != nil {
+	if err := db.UpdateRiddle(id, updatedRiddle, editorFromRequest(r), versions); err != nil {
+		var duplicate *db.DuplicateError
+		if errors.As(err, &duplicate) {
+			logger.Log.WithFields(logrus.Fields{
+				"id":          id,
+				"duplicateOf": duplicate.ID,
+				"handler":     "PatchRiddleHandler",
+			}).Warn("Rejected update duplicating another riddle")
+			problem.Write(w, r, duplicateProblem(r, duplicate))
+			return
+		}
+		if errors.Is(err, db.ErrVersionMismatch) {
+			writePreconditionFailed(w, r, id, "PatchRiddleHandler")
+			return
+		}
+		if err == sql.ErrNoRows {
+			logger.Log.WithFields(logrus.Fields{
+				"id":      id,
+				"handler": "PatchRiddleHandler",
+			}).Warn("ID not matching any riddle for update")
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+			return
+		}
 		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
 			"error":   err,
 			"handler": "PatchRiddleHandler",
-		}).Error("Error decoding request body")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		}).Error("Error updating riddle")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error updating riddle")
 		return
 	}
 
-	allowedFields := map[string]bool{
-		"riddle": true, "solution": true, "synonyms": true, "username": true, "user_email": true,
+	updated, err := db.GetAdminRiddle(id)
+	if err != nil {
+		writeRiddleError(w, r, id, err, "PatchRiddleHandler")
+		return
 	}
+	riddleResponse := adminRiddleResponse(updated, []models.Link{
+		{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		{Rel: "revisions", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/revisions", id))},
+		{Rel: "all-riddles", Href: constructURL(r, "/api/riddles")},
+	})
 
-	for field := range requestBodyMap {
-		if !allowedFields[field] {
-			logger.Log.WithFields(logrus.Fields{
-				"invalidField": field,
-				"handler":      "PatchRiddleHandler",
-			}).Warn("Invalid field in request body")
-			http.Error(w, fmt.Sprintf("Invalid field: %s", field), http.StatusBadRequest)
-			return
-		}
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"handler": "PatchRiddleHandler",
+	}).Info("Successfully executed PatchRiddleHandler")
+
+	w.Header().Set("ETag", riddleETag(r, updated.RiddleBase))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenterFor(r).riddle(riddleResponse))
+}
+
+// PutRiddleHandler replaces the whole riddle with the one in the body, fields left out are cleared.
+// The body is validated like a new riddle and may not carry fields a riddle does not have.
+// When replace.upsert is set in the config, a riddle id that does not exist is created, joining the review queue unless ?publish=true.
+func PutRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing PutRiddleHandler")
+
+	id, ok := riddleIDFromPath(w, r, "PutRiddleHandler")
+	if !ok {
+		return
+	}
+	if id < 1 {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": "PutRiddleHandler",
+		}).Warn("Riddle id out of range")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidID, "Invalid ID, riddle ids start at 1")
+		return
 	}
 
-	// marshalling the body into json, as it a the type of the map cannot be directly converted into byte
-	// the decoder cannot be referred to again, as it is a read-once stream, which has been exhausted in the map
-	requestBodyJSON, err := json.Marshal(requestBodyMap)
+	versions, ok := ifMatchVersions(w, r, id, "PutRiddleHandler")
+	if !ok {
+		return
+	}
+
+	publish, err := parseBoolParam(r.URL.Query().Get("publish"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid publish, expected true or false")
 		return
 	}
 
-	var updatedRiddle models.Riddle
-	if err := json.Unmarshal(requestBodyJSON, &updatedRiddle); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	riddle, ok := decodeRiddle(w, r, id, "PutRiddleHandler")
+	if !ok {
 		return
 	}
 
-	if err := db.UpdateRiddle(id, updatedRiddle); err != nil {
+	created, err := db.ReplaceRiddle(id, riddle, editorFromRequest(r), versions, config.AppConfig.Replace.Upsert, publish)
+	var duplicate *db.DuplicateError
+	switch {
+	case errors.As(err, &duplicate):
+		logger.Log.WithFields(logrus.Fields{
+			"id":          id,
+			"duplicateOf": duplicate.ID,
+			"handler":     "PutRiddleHandler",
+		}).Warn("Rejected duplicate riddle")
+		problem.Write(w, r, duplicateProblem(r, duplicate))
+		return
+	case errors.Is(err, db.ErrVersionMismatch):
+		writePreconditionFailed(w, r, id, "PutRiddleHandler")
+		return
+	case errors.Is(err, db.ErrInvalidRiddleID):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidID, "Invalid ID, riddle ids start at 1")
+		return
+	case errors.Is(err, db.ErrRiddleInTrash):
+		problem.Write(w, r, trashProblem(r, id, "Riddle is in the trash, restore it before replacing it"))
+		return
+	case err == sql.ErrNoRows:
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": "PutRiddleHandler",
+		}).Warn("ID not matching any riddle for replace")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+		return
+	case err != nil:
 		logger.Log.WithFields(logrus.Fields{
 			"id":      id,
 			"error":   err,
-			"handler": "PatchRiddleHandler",
-		}).Error("Error updating riddle")
-		http.Error(w, "Error updating riddle", http.StatusInternalServerError)
+			"handler": "PutRiddleHandler",
+		}).Error("Error replacing riddle")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error replacing riddle")
 		return
 	}
 
-	response := map[string]interface{}{
-		"message": "Riddle updated successfully",
-		"links": []models.Link{
-			{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
-			{Rel: "all-riddles", Href: constructURL(r, "/api/riddles")},
-		},
+	replaced, err := db.GetAdminRiddle(id)
+	if err != nil {
+		writeRiddleError(w, r, id, err, "PutRiddleHandler")
+		return
+	}
+
+	riddleResponse := adminRiddleResponse(replaced, []models.Link{
+		{Rel: "self", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		{Rel: "patch", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		{Rel: "delete", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+		{Rel: "revisions", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/revisions", id))},
+	})
+	if created {
+		riddleResponse.Warnings = duplicateWarnings(r, riddle, id)
 	}
 
 	logger.Log.WithFields(logrus.Fields{
 		"id":      id,
-		"handler": "PatchRiddleHandler",
-	}).Info("Successfully executed PatchRiddleHandler")
+		"created": created,
+		"handler": "PutRiddleHandler",
+	}).Info("Successfully executed PutRiddleHandler")
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("ETag", riddleETag(r, replaced.RiddleBase))
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.Header().Set("Location", constructURL(r, fmt.Sprintf("/api/riddles/%d", id)))
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(presenterFor(r).riddle(riddleResponse))
+}
+
+// adminRiddleResponse is the riddle an admin just wrote, with its review status when it is not public
+func adminRiddleResponse(rdl models.AdminRiddle, links []models.Link) models.RiddleResponse {
+	riddleResponse := models.RiddleResponse{
+		RiddleBase: rdl.RiddleBase,
+		Tags:       rdl.Tags,
+		Links:      links,
+	}
+	if !rdl.Published {
+		riddleResponse.Status = "pending"
+		if rdl.ReviewedAt != nil {
+			riddleResponse.Status = "rejected"
+		}
+	}
+	return riddleResponse
+}
+
+// riddleDocument is the editable part of a riddle as a JSON object. Synonyms and tags are always lists, possibly empty,
+// so JSON Patch can append to them; the submitter fields are left out when they have no value.
+func riddleDocument(rdl models.AdminRiddle) map[string]interface{} {
+	doc := map[string]interface{}{
+		"riddle":   rdl.Riddle,
+		"solution": rdl.Solution,
+		"synonyms": stringsToValues(rdl.Synonyms),
+		"tags":     stringsToValues(rdl.Tags),
+	}
+	if rdl.Username != nil {
+		doc["username"] = *rdl.Username
+	}
+	if rdl.UserEmail != nil {
+		doc["user_email"] = *rdl.UserEmail
+	}
+	return doc
+}
+
+func stringsToValues(values []string) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = value
+	}
+	return converted
+}
+
+// riddleFromDocument reads a riddle back from its JSON object, missing or null optional fields clear the stored values
+func riddleFromDocument(doc interface{}) (models.Riddle, error) {
+	var riddle models.Riddle
+
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		return riddle, problem.Validation("A riddle must be a JSON object")
+	}
+
+	var fields []problem.FieldError
+	invalid := func(field string, message string) {
+		fields = append(fields, problem.FieldError{Field: field, Message: message})
+	}
+
+	for field, value := range object {
+		switch field {
+		case "riddle", "solution", "username", "user_email":
+			text, ok := value.(string)
+			if value != nil && !ok {
+				invalid(field, "must be a string")
+				continue
+			}
+			text = strings.TrimSpace(text)
+			switch field {
+			case "riddle":
+				riddle.Riddle = text
+			case "solution":
+				riddle.Solution = text
+			case "username":
+				riddle.Username = sql.NullString{String: text, Valid: text != ""}
+			case "user_email":
+				riddle.UserEmail = sql.NullString{String: text, Valid: text != ""}
+			}
+		case "synonyms", "tags":
+			list, ok := valuesToStrings(value)
+			if !ok {
+				invalid(field, "must be a list of strings")
+				continue
+			}
+			if field == "synonyms" {
+				riddle.Synonyms = list
+			} else {
+				riddle.Tags = list
+			}
+		default:
+			invalid(field, "is not a riddle field")
+		}
+	}
+
+	if len(fields) > 0 {
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		return riddle, problem.Validation("Invalid riddle", fields...)
+	}
+	return riddle, nil
+}
+
+func valuesToStrings(value interface{}) ([]string, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	converted := make([]string, 0, len(values))
+	for _, v := range values {
+		text, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		converted = append(converted, text)
+	}
+	return converted, true
 }
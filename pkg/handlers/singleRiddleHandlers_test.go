@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/patch"
+)
+
+func TestRiddleDocumentPatch(t *testing.T) {
+	bare := models.AdminRiddle{RiddleBase: models.RiddleBase{ID: 3, Riddle: "What has a neck but no head?", Solution: "bottle"}}
+	withLists := bare
+	withLists.Synonyms = []string{"flask"}
+	withLists.Tags = []string{"objects"}
+
+	tests := []struct {
+		name         string
+		riddle       models.AdminRiddle
+		operations   string
+		wantSynonyms []string
+		wantTags     []string
+	}{
+		{"append to empty synonyms", bare, `[{"op": "add", "path": "/synonyms/-", "value": "jar"}]`, []string{"jar"}, []string{}},
+		{"append to empty tags", bare, `[{"op": "add", "path": "/tags/-", "value": "objects"}]`, []string{}, []string{"objects"}},
+		{"insert before existing", withLists, `[{"op": "add", "path": "/synonyms/0", "value": "jar"}]`, []string{"jar", "flask"}, []string{"objects"}},
+		{"empty lists can be tested", bare, `[{"op": "test", "path": "/tags", "value": []}, {"op": "add", "path": "/tags/-", "value": "objects"}]`, []string{}, []string{"objects"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operations []patch.Operation
+			if err := json.Unmarshal([]byte(tt.operations), &operations); err != nil {
+				t.Fatalf("invalid operations: %v", err)
+			}
+
+			patched, err := patch.Apply(riddleDocument(tt.riddle), operations)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			riddle, err := riddleFromDocument(patched)
+			if err != nil {
+				t.Fatalf("riddleFromDocument: %v", err)
+			}
+			if !reflect.DeepEqual(riddle.Synonyms, tt.wantSynonyms) || !reflect.DeepEqual(riddle.Tags, tt.wantTags) {
+				t.Errorf("synonyms, tags = %q, %q, want %q, %q", riddle.Synonyms, riddle.Tags, tt.wantSynonyms, tt.wantTags)
+			}
+		})
+	}
+}
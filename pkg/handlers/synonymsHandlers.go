@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/router"
+)
+
+type SynonymRequest struct {
+	Synonym string `json:"synonym"`
+}
+
+type SynonymsResponse struct {
+	ID       int           `json:"id"`
+	Synonyms []string      `json:"synonyms"`
+	Message  string        `json:"message"`
+	Links    []models.Link `json:"links,omitempty"`
+}
+
+func PostSynonymHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing PostSynonymHandler")
+
+	id, ok := riddleIDFromPath(w, r, "PostSynonymHandler")
+	if !ok {
+		return
+	}
+
+	var request SynonymRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "PostSynonymHandler",
+		}).Error("Error decoding request body")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Error parsing request body")
+		return
+	}
+
+	synonym := strings.TrimSpace(request.Synonym)
+	if synonym == "" {
+		problem.Write(w, r, problem.Validation("Missing required field: synonym", problem.FieldError{Field: "synonym", Message: "is required"}))
+		return
+	}
+
+	added, synonyms, err := db.AddSynonym(id, synonym, editorFromRequest(r))
+	if !synonymChangeOk(w, r, id, err, "PostSynonymHandler") {
+		return
+	}
+
+	status, message := http.StatusCreated, "Synonym added successfully"
+	if !added {
+		status, message = http.StatusOK, "Synonym already present"
+	}
+
+	writeSynonymsResponse(w, r, id, synonyms, message, status, "PostSynonymHandler")
+}
+
+func DeleteSynonymHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing DeleteSynonymHandler")
+
+	id, ok := riddleIDFromPath(w, r, "DeleteSynonymHandler")
+	if !ok {
+		return
+	}
+
+	// the router unescapes the segment, so synonyms containing a slash arrive whole
+	synonym := router.Param(r, "synonym")
+	if strings.TrimSpace(synonym) == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Invalid synonym")
+		return
+	}
+
+	removed, synonyms, err := db.RemoveSynonym(id, strings.TrimSpace(synonym), editorFromRequest(r))
+	if !synonymChangeOk(w, r, id, err, "DeleteSynonymHandler") {
+		return
+	}
+
+	if !removed {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"synonym": synonym,
+			"handler": "DeleteSynonymHandler",
+		}).Warn("Synonym not matching any synonym for deletion")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Synonym not found")
+		return
+	}
+
+	writeSynonymsResponse(w, r, id, synonyms, "Synonym removed successfully", http.StatusOK, "DeleteSynonymHandler")
+}
+
+// synonymChangeOk writes a 404 or 500 when adding or removing a synonym failed
+func synonymChangeOk(w http.ResponseWriter, r *http.Request, id int, err error, handler string) bool {
+	if err == sql.ErrNoRows {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": handler,
+		}).Warn("ID not matching any riddle")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found")
+		return false
+	}
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": handler,
+		}).Error("Error updating riddle synonyms")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error updating synonyms")
+		return false
+	}
+	return true
+}
+
+func writeSynonymsResponse(w http.ResponseWriter, r *http.Request, id int, synonyms []string, message string, status int, handler string) {
+	if synonyms == nil {
+		synonyms = []string{}
+	}
+
+	response := SynonymsResponse{
+		ID:       id,
+		Synonyms: synonyms,
+		Message:  message,
+		Links: []models.Link{
+			{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d?include=solution", id))},
+			{Rel: "add", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d/synonyms", id))},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"handler": handler,
+	}).Info("Successfully executed " + handler)
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+func GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetTagsHandler")
+
+	tags, err := db.ListTags()
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GetTagsHandler",
+		}).Error("Error querying tags")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	response := []models.TagCount{}
+	for _, tag := range tags {
+		tag.Links = []models.Link{
+			{Rel: "riddles", Href: constructURL(r, "/api/riddles?tag="+url.QueryEscape(tag.Name))},
+			{Rel: "random", Href: constructURL(r, "/api/riddles/random?tag="+url.QueryEscape(tag.Name))},
+		}
+		response = append(response, tag)
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"count":   len(response),
+		"handler": "GetTagsHandler",
+	}).Info("Successfully executed GetTagsHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
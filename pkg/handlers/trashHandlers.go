@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/apiversion"
+	"github.com/ionutinit/riddles-api/pkg/db"
+	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+)
+
+func GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing GetTrashHandler")
+
+	riddles, err := db.ListDeletedRiddles()
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":   err,
+			"handler": "GetTrashHandler",
+		}).Error("Error querying deleted riddles")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	response := []models.AdminRiddle{}
+	for _, rdl := range riddles {
+		rdl.Links = []models.Link{
+			{Rel: "restore", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/restore", rdl.ID))},
+		}
+		response = append(response, rdl)
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"count":   len(response),
+		"handler": "GetTrashHandler",
+	}).Info("Successfully executed GetTrashHandler")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenterFor(r).adminList(response))
+}
+
+func RestoreRiddleHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Info("Executing RestoreRiddleHandler")
+
+	id, ok := riddleIDFromPath(w, r, "RestoreRiddleHandler")
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := db.RestoreRiddle(id)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"error":   err,
+			"handler": "RestoreRiddleHandler",
+		}).Error("Error restoring riddle")
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Error restoring riddle")
+		return
+	}
+
+	if rowsAffected == 0 {
+		logger.Log.WithFields(logrus.Fields{
+			"id":      id,
+			"handler": "RestoreRiddleHandler",
+		}).Warn("ID not matching any deleted riddle")
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Riddle not found in trash")
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Riddle restored successfully",
+		"links": []models.Link{
+			{Rel: "view", Href: constructURL(r, fmt.Sprintf("/api/riddles/%d", id))},
+			{Rel: "trash", Href: constructURL(r, "/api/admin/riddles/trash")},
+		},
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"id":      id,
+		"handler": "RestoreRiddleHandler",
+	}).Info("Successfully executed RestoreRiddleHandler")
+
+	apiversion.Unversioned(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// trashProblem refuses a change to a riddle in the trash, linking to its restore
+func trashProblem(r *http.Request, id int, detail string) *problem.Problem {
+	p := problem.New(http.StatusConflict, problem.CodeConflict, detail)
+	p.Links = []models.Link{
+		{Rel: "restore", Href: constructURL(r, fmt.Sprintf("/api/admin/riddles/%d/restore", id))},
+	}
+	return p
+}
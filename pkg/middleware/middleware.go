@@ -7,6 +7,8 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/ionutinit/riddles-api/pkg/logger"
+	"github.com/ionutinit/riddles-api/pkg/problem"
+	"github.com/ionutinit/riddles-api/pkg/router"
 )
 
 func IPWhitelistMiddleware(next http.Handler, allowedIPs []string) http.Handler {
@@ -17,7 +19,7 @@ func IPWhitelistMiddleware(next http.Handler, allowedIPs []string) http.Handler
 				"clientIP": clientIP,
 				"error":    err.Error(),
 			}).Error("Invalid client IP address")
-			http.Error(w, "Invalid address", http.StatusBadRequest)
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "Invalid address")
 			return
 		}
 
@@ -25,7 +27,7 @@ func IPWhitelistMiddleware(next http.Handler, allowedIPs []string) http.Handler
 			logger.Log.WithFields(logrus.Fields{
 				"clientIP": clientIP,
 			}).Warning("Access to DELETE/PATCH methods denied due to IP restrictions")
-			http.Error(w, "Access denied", http.StatusForbidden)
+			problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, "Access denied")
 			return
 		}
 
@@ -41,3 +43,54 @@ func isIPAllowed(clientIP string, allowedIPs []string) bool {
 	}
 	return false
 }
+
+// IsRequestAllowed tells handlers serving both the public and admins whether the client is on the whitelist
+func IsRequestAllowed(r *http.Request, allowedIPs []string) bool {
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	return isIPAllowed(clientIP, allowedIPs)
+}
+
+// CacheControlMiddleware sets the Cache-Control policy of the matched route, looked up by its pattern.
+// Only successful and 304 responses carry it, errors must not be cached for as long as the resource.
+func CacheControlMiddleware(next http.Handler, policies map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := policies[router.Pattern(r)]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, policy: policy}, r)
+	})
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status < http.StatusBadRequest && w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", w.policy)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheControlWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
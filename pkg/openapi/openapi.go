@@ -0,0 +1,8 @@
+// Package openapi embeds the OpenAPI 3 document describing every route of the API.
+// The document is written by hand, main_test.go checks it against the registered routes.
+package openapi
+
+import _ "embed"
+
+//go:embed openapi.json
+var Spec []byte
@@ -0,0 +1,125 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return v
+}
+
+// the examples of RFC 7396, appendix A
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" + "+tt.patch, func(t *testing.T) {
+			target := decode(t, tt.target)
+			got := Merge(target, decode(t, tt.patch))
+			if !reflect.DeepEqual(got, decode(t, tt.want)) {
+				t.Errorf("Merge = %v, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(target, decode(t, tt.target)) {
+				t.Errorf("Merge changed its target to %v", target)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		ops  string
+		want string
+		// failing is the index of the operation expected to fail, -1 when the patch applies
+		failing int
+	}{
+		{"add member", `{"riddle":"x"}`, `[{"op":"add","path":"/solution","value":"y"}]`, `{"riddle":"x","solution":"y"}`, -1},
+		{"add replaces member", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`, -1},
+		{"add inserts into array", `{"s":["a","c"]}`, `[{"op":"add","path":"/s/1","value":"b"}]`, `{"s":["a","b","c"]}`, -1},
+		{"add appends with dash", `{"s":["a"]}`, `[{"op":"add","path":"/s/-","value":"b"}]`, `{"s":["a","b"]}`, -1},
+		{"add at array length", `{"s":["a"]}`, `[{"op":"add","path":"/s/1","value":"b"}]`, `{"s":["a","b"]}`, -1},
+		{"add past array end", `{"s":["a"]}`, `[{"op":"add","path":"/s/2","value":"b"}]`, ``, 0},
+		{"add null value", `{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`, -1},
+		{"add without value", `{}`, `[{"op":"add","path":"/a"}]`, ``, 0},
+		{"add to missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ``, 0},
+		{"add whole document", `{"a":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`, -1},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`, -1},
+		{"remove array item", `{"s":["a","b","c"]}`, `[{"op":"remove","path":"/s/1"}]`, `{"s":["a","c"]}`, -1},
+		{"remove missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, ``, 0},
+		{"remove leading zero index", `{"s":["a","b"]}`, `[{"op":"remove","path":"/s/01"}]`, ``, 0},
+		{"replace member", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`, -1},
+		{"replace missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, ``, 0},
+		{"replace array item", `{"s":[1,2]}`, `[{"op":"replace","path":"/s/0","value":3}]`, `{"s":[3,2]}`, -1},
+		{"move member", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`, -1},
+		{"move array item", `{"s":["a","b","c"]}`, `[{"op":"move","from":"/s/0","path":"/s/2"}]`, `{"s":["b","c","a"]}`, -1},
+		{"move into itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ``, 0},
+		{"copy member", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, -1},
+		{"test equal", `{"a":[1,{"b":"c"}]}`, `[{"op":"test","path":"/a","value":[1,{"b":"c"}]}]`, `{"a":[1,{"b":"c"}]}`, -1},
+		{"test different", `{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`, ``, 0},
+		{"test missing", `{}`, `[{"op":"test","path":"/a","value":null}]`, ``, 0},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/m~0n"}]`, `{}`, -1},
+		{"invalid pointer", `{"a":1}`, `[{"op":"remove","path":"a"}]`, ``, 0},
+		{"unknown op", `{"a":1}`, `[{"op":"increment","path":"/a"}]`, ``, 0},
+		{"later operation fails", `{"a":1}`, `[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":1}]`, ``, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatalf("invalid operations: %v", err)
+			}
+			doc := decode(t, tt.doc)
+
+			got, err := Apply(doc, ops)
+			if !reflect.DeepEqual(doc, decode(t, tt.doc)) {
+				t.Errorf("Apply changed its input to %v", doc)
+			}
+
+			if tt.failing >= 0 {
+				patchErr, ok := err.(*Error)
+				if !ok {
+					t.Fatalf("Apply = %v, %v, want an error at operation %d", got, err, tt.failing)
+				}
+				if patchErr.Index != tt.failing || patchErr.Op != ops[tt.failing].Op {
+					t.Errorf("error at operation %d (%s), want %d", patchErr.Index, patchErr.Op, tt.failing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, decode(t, tt.want)) {
+				t.Errorf("Apply = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package problem writes errors as RFC 7807 application/problem+json documents.
+//
+// Every problem carries a stable machine-readable code next to the human readable detail,
+// the request id and, for validation errors, the fields at fault.
+package problem
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ionutinit/riddles-api/models"
+	"github.com/ionutinit/riddles-api/pkg/requestid"
+)
+
+const ContentType = "application/problem+json"
+
+// stable codes clients can rely on, the detail text may change
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeInvalidID            = "invalid_id"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeValidation           = "validation_failed"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeDuplicate            = "duplicate_riddle"
+	CodeGone                 = "gone"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeTooLarge             = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnprocessable        = "unprocessable"
+	CodePreconditionRequired = "precondition_required"
+	CodeInternal             = "internal_error"
+	CodeUpstream             = "upstream_error"
+)
+
+// FieldError points to one invalid field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// related resources, e.g. the existing riddle a submission duplicates
+	Links []models.Link `json:"links,omitempty"`
+}
+
+// New returns a problem for the status, the title is the status text
+func New(status int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Validation returns a 400 listing the invalid fields
+func Validation(detail string, fields ...FieldError) *Problem {
+	p := New(http.StatusBadRequest, CodeValidation, detail)
+	p.Errors = fields
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// FromError maps an error to a problem: problems pass through, sql.ErrNoRows is a 404
+// and anything else a 500 that does not leak the underlying error
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return New(http.StatusNotFound, CodeNotFound, "Resource not found")
+	}
+	return New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+// Write sends the problem, filling in the request path and id
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	response := *p
+	response.Instance = r.URL.Path
+	response.RequestID = requestid.FromRequest(r)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(response.Status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Error is the problem+json counterpart of http.Error
+func Error(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	Write(w, r, New(status, code, detail))
+}
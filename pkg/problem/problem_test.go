@@ -0,0 +1,82 @@
+package problem
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ionutinit/riddles-api/pkg/requestid"
+)
+
+func TestFromError(t *testing.T) {
+	validation := Validation("Invalid riddle", FieldError{Field: "riddle", Message: "is required"})
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"problem passes through", validation, http.StatusBadRequest, CodeValidation},
+		{"wrapped problem", fmt.Errorf("saving: %w", validation), http.StatusBadRequest, CodeValidation},
+		{"no rows", sql.ErrNoRows, http.StatusNotFound, CodeNotFound},
+		{"wrapped no rows", fmt.Errorf("loading: %w", sql.ErrNoRows), http.StatusNotFound, CodeNotFound},
+		{"anything else", errors.New("pq: connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := FromError(tt.err)
+			if p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("FromError(%v) = %d %s, want %d %s", tt.err, p.Status, p.Code, tt.status, tt.code)
+			}
+			if p.Status == http.StatusInternalServerError && p.Detail != "Internal server error" {
+				t.Errorf("500 leaks the error: %q", p.Detail)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, Validation("Invalid riddle", FieldError{Field: "solution", Message: "is required"}))
+	}))
+
+	req := httptest.NewRequest("POST", "/api/riddles", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ContentType)
+	}
+
+	var body Problem
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid problem document: %v", err)
+	}
+	want := Problem{
+		Type:      "about:blank",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "Invalid riddle",
+		Instance:  "/api/riddles",
+		Code:      CodeValidation,
+		RequestID: "abc-123",
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Field != "solution" {
+		t.Errorf("errors = %+v, want the solution field", body.Errors)
+	}
+	body.Errors = nil
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("problem = %+v, want %+v", body, want)
+	}
+}
@@ -0,0 +1,49 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{"client id is kept", "req-42", true},
+		{"missing id is generated", "", false},
+		{"spaces are refused", "req 42", false},
+		{"control characters are refused", "req\x0142", false},
+		{"long ids are refused", strings.Repeat("a", maxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = FromRequest(r)
+			}))
+
+			req := httptest.NewRequest("GET", "/api/riddles", nil)
+			if tt.sent != "" {
+				req.Header.Set(Header, tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			header := rec.Header().Get(Header)
+			if header != seen {
+				t.Errorf("header %q differs from the id in the context %q", header, seen)
+			}
+			if tt.keep && seen != tt.sent {
+				t.Errorf("id = %q, want the client's %q", seen, tt.sent)
+			}
+			if !tt.keep && (seen == tt.sent || len(seen) != 32) {
+				t.Errorf("id = %q, want a generated one", seen)
+			}
+		})
+	}
+}
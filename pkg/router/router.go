@@ -0,0 +1,325 @@
+// Package router matches requests on method and path pattern, extracting the path parameters once.
+//
+// Patterns are made of static segments and parameters, e.g. /api/riddles/{id:int}/hints/{n:int}.
+// A parameter matches any single segment, {name:int} only digits, and a final {name...} the rest of the path.
+// Trailing slashes are ignored, so /api/riddles/ and /api/riddles are the same route.
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Middleware wraps the handler of a single route
+type Middleware func(http.Handler) http.Handler
+
+type segment struct {
+	value string
+	param bool
+	// integer parameters only match digits
+	integer bool
+	// rest parameters match every remaining segment
+	rest bool
+}
+
+type route struct {
+	method   string
+	pattern  string
+	segments []segment
+	handler  http.Handler
+	// GET routes answer HEAD too, unless running the handler changes something
+	noHead bool
+}
+
+type Router struct {
+	routes []*route
+	// NotFound answers requests matching no route, defaults to http.NotFound
+	NotFound http.Handler
+	// MethodNotAllowed answers requests whose path matches but not with this method, the Allow header is already set
+	MethodNotAllowed http.Handler
+}
+
+func New() *Router {
+	return &Router{}
+}
+
+// Handle registers the handler for the method and pattern, the middleware is applied in order, the first one outermost
+func (rt *Router) Handle(method string, pattern string, handler http.Handler, middleware ...Middleware) RouteOptions {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	r := &route{
+		method:   strings.ToUpper(method),
+		pattern:  pattern,
+		segments: parsePattern(pattern),
+		handler:  handler,
+	}
+	rt.routes = append(rt.routes, r)
+	return RouteOptions{route: r}
+}
+
+func (rt *Router) HandleFunc(method string, pattern string, handler http.HandlerFunc, middleware ...Middleware) RouteOptions {
+	return rt.Handle(method, pattern, handler, middleware...)
+}
+
+// RouteOptions adjusts a route once it is registered
+type RouteOptions struct {
+	route *route
+}
+
+// WithoutHead keeps a GET route from answering HEAD, for handlers that change something, HEAD then gets a 405.
+// Monitors and link checkers send HEAD freely and must not trigger those changes.
+func (o RouteOptions) WithoutHead() {
+	o.route.noHead = true
+}
+
+// Group registers routes under a common prefix, its middleware wraps the middleware of each route
+type Group struct {
+	router     *Router
+	prefix     string
+	middleware []Middleware
+}
+
+// Group starts a group of routes, e.g. rt.Group("/api/v2").HandleFunc("GET", "/riddles", ...) registers /api/v2/riddles
+func (rt *Router) Group(prefix string, middleware ...Middleware) *Group {
+	return &Group{router: rt, prefix: strings.TrimSuffix(prefix, "/"), middleware: middleware}
+}
+
+func (g *Group) Handle(method string, pattern string, handler http.Handler, middleware ...Middleware) RouteOptions {
+	chain := append(append([]Middleware{}, g.middleware...), middleware...)
+	return g.router.Handle(method, g.prefix+pattern, handler, chain...)
+}
+
+func (g *Group) HandleFunc(method string, pattern string, handler http.HandlerFunc, middleware ...Middleware) RouteOptions {
+	return g.Handle(method, pattern, handler, middleware...)
+}
+
+// Route describes a registered route
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// Routes lists the registered routes in registration order
+func (rt *Router) Routes() []Route {
+	routes := make([]Route, 0, len(rt.routes))
+	for _, r := range rt.routes {
+		routes = append(routes, Route{Method: r.method, Pattern: r.pattern})
+	}
+	return routes
+}
+
+func parsePattern(pattern string) []segment {
+	var segments []segment
+	for _, part := range splitPath(pattern) {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			segments = append(segments, segment{value: part})
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(part, "{"), "}")
+		seg := segment{param: true}
+		switch {
+		case strings.HasSuffix(name, "..."):
+			seg.rest = true
+			name = strings.TrimSuffix(name, "...")
+		case strings.HasSuffix(name, ":int"):
+			seg.integer = true
+			name = strings.TrimSuffix(name, ":int")
+		}
+		seg.value = name
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// match returns the parameters when the path fits the route, with how many static segments it matched
+func (r *route) match(parts []string) (map[string]string, int, bool) {
+	params := map[string]string{}
+	static := 0
+
+	for i, seg := range r.segments {
+		if seg.rest {
+			if i > len(parts) {
+				return nil, 0, false
+			}
+			rest := make([]string, 0, len(parts)-i)
+			for _, part := range parts[i:] {
+				unescaped, err := url.PathUnescape(part)
+				if err != nil {
+					return nil, 0, false
+				}
+				rest = append(rest, unescaped)
+			}
+			params[seg.value] = strings.Join(rest, "/")
+			return params, static, true
+		}
+
+		if i >= len(parts) {
+			return nil, 0, false
+		}
+
+		// parts come from the escaped path, so an encoded slash stays inside its segment
+		value, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, 0, false
+		}
+
+		switch {
+		case !seg.param:
+			if value != seg.value {
+				return nil, 0, false
+			}
+			static++
+		case seg.integer:
+			if _, err := strconv.Atoi(value); err != nil || strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+				return nil, 0, false
+			}
+			params[seg.value] = value
+		default:
+			if value == "" {
+				return nil, 0, false
+			}
+			params[seg.value] = value
+		}
+	}
+
+	if len(parts) != len(r.segments) {
+		return nil, 0, false
+	}
+	return params, static, true
+}
+
+type paramsKey struct{}
+
+type patternKey struct{}
+
+// Pattern returns the pattern of the matched route, e.g. /api/riddles/{id:int}
+func Pattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(patternKey{}).(string)
+	return pattern
+}
+
+// Param returns the named path parameter of the matched route, or "" when there is none
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// IntParam returns an integer path parameter, {name:int} routes guarantee it parses
+func IntParam(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(Param(r, name))
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(r.URL.EscapedPath())
+
+	var (
+		best       *route
+		bestParams map[string]string
+		bestStatic = -1
+		get        *route
+		getParams  map[string]string
+		getStatic  = -1
+		allowed    = map[string]bool{}
+	)
+
+	for _, candidate := range rt.routes {
+		params, static, ok := candidate.match(parts)
+		if !ok {
+			continue
+		}
+		allowed[candidate.method] = true
+
+		// the most specific route wins, registration order breaks ties
+		if candidate.method == r.Method && static > bestStatic {
+			best, bestParams, bestStatic = candidate, params, static
+		}
+		if candidate.method == http.MethodGet && static > getStatic {
+			get, getParams, getStatic = candidate, params, static
+		}
+	}
+
+	// HEAD is served by the GET route without the body, unless the route opted out
+	headServedByGet := false
+	if get != nil && !get.noHead {
+		allowed[http.MethodHead] = true
+		if r.Method == http.MethodHead && best == nil {
+			best, bestParams, headServedByGet = get, getParams, true
+		}
+	}
+
+	if len(allowed) == 0 {
+		if rt.NotFound != nil {
+			rt.NotFound.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	allow := allowHeader(allowed)
+
+	if r.Method == http.MethodOptions && best == nil {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if best == nil {
+		w.Header().Set("Allow", allow)
+		if rt.MethodNotAllowed != nil {
+			rt.MethodNotAllowed.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), paramsKey{}, bestParams)
+	r = r.WithContext(context.WithValue(ctx, patternKey{}, best.pattern))
+	if headServedByGet {
+		w = &headResponseWriter{ResponseWriter: w}
+	}
+	best.handler.ServeHTTP(w, r)
+}
+
+func allowHeader(allowed map[string]bool) string {
+	allowed[http.MethodOptions] = true
+
+	methods := make([]string, 0, len(allowed))
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// headResponseWriter drops the body while keeping the headers and status of the GET handler
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Flush lets streaming handlers keep flushing under HEAD
+func (w *headResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,211 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// named answers with the name of the route, so tests can tell which one matched
+func named(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, name)
+	}
+}
+
+func serve(rt *Router, method string, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestPrecedence(t *testing.T) {
+	rt := New()
+	rt.HandleFunc("GET", "/api/riddles/{id:int}", named("by id"))
+	rt.HandleFunc("GET", "/api/riddles/random", named("random"))
+	rt.HandleFunc("GET", "/api/riddles/{slug}", named("by slug"))
+	rt.HandleFunc("GET", "/api/riddles/{id:int}/hints/{n:int}", named("hint"))
+	rt.HandleFunc("GET", "/static/{path...}", named("static"))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/riddles/42", "by id"},
+		{"/api/riddles/random", "random"},
+		{"/api/riddles/random/", "random"},
+		{"/api/riddles/enigma", "by slug"},
+		{"/api/riddles/-1", "by slug"},
+		{"/api/riddles/42/hints/2", "hint"},
+		{"/static/css/site.css", "static"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(rt, "GET", tt.path)
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+				t.Errorf("GET %s = %d %q, want %q", tt.path, rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	rt := New()
+	var got map[string]string
+	rt.HandleFunc("GET", "/api/riddles/{id:int}/synonyms/{synonym}", func(w http.ResponseWriter, r *http.Request) {
+		got = map[string]string{"id": Param(r, "id"), "synonym": Param(r, "synonym"), "pattern": Pattern(r)}
+	})
+	rt.HandleFunc("GET", "/static/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		got = map[string]string{"path": Param(r, "path")}
+	})
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{"/api/riddles/7/synonyms/big%20cat", map[string]string{"id": "7", "synonym": "big cat", "pattern": "/api/riddles/{id:int}/synonyms/{synonym}"}},
+		{"/api/riddles/7/synonyms/a%2Fb", map[string]string{"id": "7", "synonym": "a/b", "pattern": "/api/riddles/{id:int}/synonyms/{synonym}"}},
+		{"/static/img/logo.png", map[string]string{"path": "img/logo.png"}},
+	}
+
+	for _, tt := range tests {
+		got = nil
+		if rec := serve(rt, "GET", tt.path); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d, want 200", tt.path, rec.Code)
+		}
+		for name, want := range tt.want {
+			if got[name] != want {
+				t.Errorf("GET %s: %s = %q, want %q", tt.path, name, got[name], want)
+			}
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	rt := New()
+	rt.HandleFunc("GET", "/api/riddles/{id:int}", named("get"))
+	rt.HandleFunc("DELETE", "/api/riddles/{id:int}", named("delete"))
+	rt.HandleFunc("POST", "/api/riddles", named("post"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		allow  string
+		body   string
+	}{
+		{"matching method", "DELETE", "/api/riddles/1", http.StatusOK, "", "delete"},
+		{"HEAD served by GET without body", "HEAD", "/api/riddles/1", http.StatusOK, "", ""},
+		{"wrong method", "PUT", "/api/riddles/1", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS", ""},
+		{"OPTIONS lists methods", "OPTIONS", "/api/riddles/1", http.StatusNoContent, "DELETE, GET, HEAD, OPTIONS", ""},
+		{"no HEAD without GET", "OPTIONS", "/api/riddles", http.StatusNoContent, "OPTIONS, POST", ""},
+		{"int parameter refuses words", "GET", "/api/riddles/abc", http.StatusNotFound, "", ""},
+		{"unknown path", "GET", "/api/nothing", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(rt, tt.method, tt.path)
+			if rec.Code != tt.status {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, allow, tt.allow)
+			}
+			if tt.body != "" || tt.method == "HEAD" {
+				if rec.Body.String() != tt.body {
+					t.Errorf("%s %s: body = %q, want %q", tt.method, tt.path, rec.Body.String(), tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestWithoutHead(t *testing.T) {
+	rt := New()
+	calls := 0
+	rt.HandleFunc("GET", "/api/sessions/{id}/next", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.WriteString(w, "next")
+	}).WithoutHead()
+	rt.Group("/api/v2").HandleFunc("GET", "/sessions/{id}/next", named("next")).WithoutHead()
+	rt.HandleFunc("GET", "/api/sessions/{id}", named("session"))
+
+	rec := serve(rt, "HEAD", "/api/sessions/abc/next")
+	if rec.Code != http.StatusMethodNotAllowed || calls != 0 {
+		t.Errorf("HEAD = %d with %d calls, want 405 without running the handler", rec.Code, calls)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, OPTIONS" {
+		t.Errorf("Allow = %q, want GET, OPTIONS", allow)
+	}
+	if rec := serve(rt, "HEAD", "/api/v2/sessions/abc/next"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("HEAD in group = %d, want 405", rec.Code)
+	}
+
+	if rec := serve(rt, "GET", "/api/sessions/abc/next"); rec.Code != http.StatusOK || calls != 1 {
+		t.Errorf("GET = %d with %d calls, want 200 and one call", rec.Code, calls)
+	}
+	// other GET routes keep answering HEAD
+	if rec := serve(rt, "HEAD", "/api/sessions/abc"); rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD of another route = %d %q, want 200 without body", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCustomNotFoundAndMethodNotAllowed(t *testing.T) {
+	rt := New()
+	rt.HandleFunc("GET", "/api/tags", named("tags"))
+	rt.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	rt.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	if rec := serve(rt, "GET", "/api/missing"); rec.Code != http.StatusTeapot {
+		t.Errorf("NotFound handler not used, got %d", rec.Code)
+	}
+	rec := serve(rt, "POST", "/api/tags")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("MethodNotAllowed handler not used, got %d", rec.Code)
+	}
+	if rec.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("Allow = %q, want it set before MethodNotAllowed runs", rec.Header().Get("Allow"))
+	}
+}
+
+func TestGroupMiddlewareOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	rt := New()
+	rt.Group("/api/v2/", trace("group")).HandleFunc("GET", "/riddles", named("riddles"), trace("first"), trace("second"))
+
+	rec := serve(rt, "GET", "/api/v2/riddles")
+	if rec.Body.String() != "riddles" {
+		t.Fatalf("GET /api/v2/riddles = %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rt.Routes()[0].Pattern; got != "/api/v2/riddles" {
+		t.Errorf("pattern = %q, want /api/v2/riddles", got)
+	}
+
+	want := []string{"group", "first", "second"}
+	if len(order) != len(want) {
+		t.Fatalf("middleware ran %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("middleware ran %v, want %v", order, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE daily_riddles (
+    day DATE PRIMARY KEY,
+    riddle_id INT NOT NULL REFERENCES riddles(id) ON DELETE CASCADE,
+    -- true when an admin picked the riddle, false when it was picked automatically
+    scheduled BOOLEAN NOT NULL DEFAULT false,
+    date_created TIMESTAMP DEFAULT NOW()
+)
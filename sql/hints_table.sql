@@ -0,0 +1,9 @@
+CREATE TABLE hints (
+    id SERIAL PRIMARY KEY,
+    riddle_id INT NOT NULL REFERENCES riddles(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    hint TEXT NOT NULL,
+    date_created TIMESTAMP DEFAULT NOW(),
+    -- deferred so that positions can be shuffled inside a single transaction when reordering
+    UNIQUE (riddle_id, position) DEFERRABLE INITIALLY DEFERRED
+)
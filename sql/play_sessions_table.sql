@@ -0,0 +1,8 @@
+CREATE TABLE play_sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    -- published riddle ids shuffled once at creation, served in order
+    riddle_ids INT[] NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    date_created TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+)
@@ -0,0 +1,29 @@
+-- a snapshot of the riddle after each change, numbered per riddle
+CREATE TABLE riddle_revisions (
+    id SERIAL PRIMARY KEY,
+    riddle_id INT NOT NULL REFERENCES riddles(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    riddle TEXT NOT NULL,
+    solution VARCHAR(255) NOT NULL,
+    synonyms TEXT,
+    username VARCHAR(255) DEFAULT NULL,
+    user_email VARCHAR(255) DEFAULT NULL,
+    tags TEXT[],
+    -- create, update or revert, baseline for the state found before the first tracked change
+    change_type VARCHAR(32) NOT NULL,
+    changed_by VARCHAR(255) DEFAULT NULL,
+    date_created TIMESTAMP DEFAULT NOW(),
+    UNIQUE (riddle_id, revision)
+);
+
+
+-- synonyms as a list, matching the riddles table
+ALTER TABLE riddle_revisions ALTER COLUMN synonyms TYPE TEXT[] USING
+    CASE
+        WHEN synonyms IS NULL OR btrim(synonyms) = '' THEN NULL
+        ELSE array_remove(regexp_split_to_array(btrim(synonyms), '\s*,\s*'), '')
+    END;
+
+
+-- baselines used to record an unknown editor as an empty string
+UPDATE riddle_revisions SET changed_by = NULL WHERE changed_by = '';
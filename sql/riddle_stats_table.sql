@@ -0,0 +1,6 @@
+-- kept apart from riddles so that counting guesses does not touch last_modified
+CREATE TABLE riddle_stats (
+    riddle_id INT PRIMARY KEY REFERENCES riddles(id) ON DELETE CASCADE,
+    guesses INT NOT NULL DEFAULT 0,
+    solves INT NOT NULL DEFAULT 0
+)
@@ -25,3 +25,85 @@ CREATE TRIGGER update_last_modified
 BEFORE UPDATE ON riddles
 FOR EACH ROW
 EXECUTE PROCEDURE update_modified_column();
+
+
+-- full-text search, the riddle is weighted A and the solution with its synonyms B,
+-- so queries can leave out the solutions by only matching A lexemes
+ALTER TABLE riddles ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION update_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector('english', coalesce(NEW.riddle, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.solution, '') || ' ' || coalesce(NEW.synonyms, '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_search_vector
+BEFORE INSERT OR UPDATE ON riddles
+FOR EACH ROW
+EXECUTE PROCEDURE update_search_vector();
+
+-- backfill existing rows without touching last_modified
+ALTER TABLE riddles DISABLE TRIGGER update_last_modified;
+UPDATE riddles SET riddle = riddle;
+ALTER TABLE riddles ENABLE TRIGGER update_last_modified;
+
+CREATE INDEX IF NOT EXISTS riddles_search_idx ON riddles USING GIN (search_vector);
+
+
+-- moderation, submissions stay unpublished until reviewed
+-- pending riddles are the unpublished ones that were never reviewed
+ALTER TABLE riddles ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(255) DEFAULT NULL;
+ALTER TABLE riddles ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE riddles ADD COLUMN IF NOT EXISTS rejection_reason TEXT DEFAULT NULL;
+
+
+-- soft delete, deleted riddles stay in the trash until purged after the retention period
+ALTER TABLE riddles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS riddles_deleted_at_idx ON riddles (deleted_at) WHERE deleted_at IS NOT NULL;
+
+
+-- synonyms as a list, converting the comma separated values
+ALTER TABLE riddles ALTER COLUMN synonyms TYPE TEXT[] USING
+    CASE
+        WHEN synonyms IS NULL OR btrim(synonyms) = '' THEN NULL
+        ELSE array_remove(regexp_split_to_array(btrim(synonyms), '\s*,\s*'), '')
+    END;
+
+CREATE OR REPLACE FUNCTION update_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector('english', coalesce(NEW.riddle, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.solution, '') || ' ' || coalesce(array_to_string(NEW.synonyms, ' '), '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- duplicate detection, exact duplicates share a fingerprint, near duplicates are found by trigram similarity
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE OR REPLACE FUNCTION riddle_fingerprint(txt TEXT)
+RETURNS TEXT AS $$
+    SELECT btrim(regexp_replace(lower(coalesce(txt, '')), '[^[:alnum:]]+', ' ', 'g'));
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE INDEX IF NOT EXISTS riddles_fingerprint_idx ON riddles (riddle_fingerprint(riddle)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS riddles_riddle_trgm_idx ON riddles USING GIN (riddle gin_trgm_ops);
+
+
+-- optimistic concurrency, every change to a riddle bumps its version, which the API exposes as the ETag
+ALTER TABLE riddles ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.last_modified = NOW();
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,150 @@
+// A small explorer for the OpenAPI document, served with the API so the page loads nothing from third parties.
+// Every operation lists its parameters and can be sent from the page, the response is shown as it comes.
+(function () {
+  var container = document.getElementById("explorer");
+  var specURL = container.getAttribute("data-spec");
+
+  function element(tag, className, text) {
+    var el = document.createElement(tag);
+    if (className) {
+      el.className = className;
+    }
+    if (text !== undefined) {
+      el.textContent = text;
+    }
+    return el;
+  }
+
+  // resolve follows a local $ref such as #/components/parameters/id
+  function resolve(spec, node) {
+    while (node && node.$ref) {
+      node = node.$ref.replace(/^#\//, "").split("/").reduce(function (current, key) {
+        return current[key];
+      }, spec);
+    }
+    return node;
+  }
+
+  function operation(spec, path, method, op) {
+    var details = element("details", "operation");
+    var summary = element("summary");
+    summary.appendChild(element("span", "method method-" + method, method.toUpperCase()));
+    summary.appendChild(element("code", "", path));
+    summary.appendChild(element("span", "summary", op.summary || ""));
+    if (op["x-restricted"]) {
+      summary.appendChild(element("span", "restricted", "restricted"));
+    }
+    details.appendChild(summary);
+
+    if (op.description) {
+      details.appendChild(element("p", "", op.description));
+    }
+
+    var form = element("form");
+    var inputs = [];
+    (op.parameters || []).forEach(function (ref) {
+      var param = resolve(spec, ref);
+      var label = element("label", "", param.name + " (" + param.in + (param.required ? ", required" : "") + ")");
+      var input = element("input");
+      input.name = param.name;
+      input.placeholder = param.description || "";
+      input.required = !!param.required;
+      label.appendChild(input);
+      form.appendChild(label);
+      inputs.push({ param: param, input: input });
+    });
+
+    var body;
+    if (op.requestBody) {
+      var content = resolve(spec, op.requestBody).content || {};
+      var mediaType = Object.keys(content)[0];
+      body = element("textarea");
+      body.rows = 6;
+      body.placeholder = mediaType + " body";
+      body.setAttribute("data-type", mediaType);
+      form.appendChild(body);
+    }
+
+    var send = element("button", "", "Send");
+    send.type = "submit";
+    form.appendChild(send);
+    var output = element("pre", "response");
+    form.appendChild(output);
+
+    form.addEventListener("submit", function (event) {
+      event.preventDefault();
+
+      var url = path;
+      var query = new URLSearchParams();
+      var headers = {};
+      inputs.forEach(function (entry) {
+        var value = entry.input.value;
+        if (value === "") {
+          return;
+        }
+        if (entry.param.in === "path") {
+          url = url.replace("{" + entry.param.name + "}", encodeURIComponent(value));
+        } else if (entry.param.in === "query") {
+          query.append(entry.param.name, value);
+        } else if (entry.param.in === "header") {
+          headers[entry.param.name] = value;
+        }
+      });
+      if (query.toString() !== "") {
+        url += "?" + query.toString();
+      }
+
+      var init = { method: method.toUpperCase(), headers: headers };
+      if (body && body.value !== "") {
+        headers["Content-Type"] = body.getAttribute("data-type");
+        init.body = body.value;
+      }
+
+      output.textContent = init.method + " " + url + " ...";
+      fetch(url, init)
+        .then(function (response) {
+          return response.text().then(function (text) {
+            var lines = [response.status + " " + response.statusText];
+            response.headers.forEach(function (value, name) {
+              lines.push(name + ": " + value);
+            });
+            try {
+              text = JSON.stringify(JSON.parse(text), null, 2);
+            } catch (e) {
+              // not JSON, shown as it came
+            }
+            output.textContent = lines.join("\n") + "\n\n" + text;
+          });
+        })
+        .catch(function (error) {
+          output.textContent = "Request failed: " + error;
+        });
+    });
+
+    details.appendChild(form);
+    return details;
+  }
+
+  fetch(specURL)
+    .then(function (response) {
+      return response.json();
+    })
+    .then(function (spec) {
+      var sections = {};
+      Object.keys(spec.paths).forEach(function (path) {
+        Object.keys(spec.paths[path]).forEach(function (method) {
+          var op = spec.paths[path][method];
+          var tag = (op.tags && op.tags[0]) || "Other";
+          if (!sections[tag]) {
+            sections[tag] = element("section", "tag");
+            sections[tag].appendChild(element("h4", "", tag));
+            container.appendChild(sections[tag]);
+          }
+          sections[tag].appendChild(operation(spec, path, method, op));
+        });
+      });
+    })
+    .catch(function (error) {
+      container.textContent = "The OpenAPI document could not be loaded: " + error;
+    });
+})();